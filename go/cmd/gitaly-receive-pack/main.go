@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/handler"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
 	"google.golang.org/grpc"
@@ -16,13 +17,13 @@ func init() {
 }
 
 func main() {
-	handler.RunGitalyCommand(func(ctx context.Context, conn *grpc.ClientConn, requestJSON string) (int32, error) {
+	handler.RunGitalyCommand(func(ctx context.Context, conn *grpc.ClientConn, readWriter *readwriter.ReadWriter, requestJSON string) (int32, error) {
 		request, err := deserialize(requestJSON)
 		if err != nil {
 			return 1, err
 		}
 
-		return handler.ReceivePack(ctx, conn, request)
+		return handler.ReceivePack(ctx, conn, readWriter, request)
 	})
 }
 
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/authorizedkeys"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+const (
+	// feature is the migration feature enabling the Go implementation
+	feature     = "authorized_keys_check"
+	rubyProgram = "gitlab-shell-authorized-keys-check-ruby"
+)
+
+// execRuby will never return. It either replaces the current process with a
+// Ruby interpreter, or outputs an error and kills the process.
+func execRuby(rootDir string, args []string, readWriter *readwriter.ReadWriter) {
+	cmd := &fallback.Command{RootDir: rootDir, Args: args, Program: rubyProgram}
+
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(readWriter.ErrOut, "Failed to exec: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	readWriter := &readwriter.ReadWriter{
+		Out:    os.Stdout,
+		In:     os.Stdin,
+		ErrOut: os.Stderr,
+	}
+
+	flags, args, err := config.ParseFlags(os.Args)
+	if err != nil {
+		fmt.Fprintf(readWriter.ErrOut, "# %v\n", err)
+		os.Exit(1)
+	}
+
+	paths, err := config.FindPaths(flags, config.RootDirFromExecutable)
+	if err != nil {
+		fmt.Fprintln(readWriter.ErrOut, "# Failed to determine root directory, exiting")
+		os.Exit(1)
+	}
+
+	// The Ruby implementation must use the same paths
+	if err := paths.Export(); err != nil {
+		fmt.Fprintf(readWriter.ErrOut, "# Failed to export the paths: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Anything written to stdout ends up in sshd's list of authorized keys,
+	// so problems reading the config only go to stderr
+	config, err := config.NewFromPaths(paths)
+	if err != nil {
+		fmt.Fprintln(readWriter.ErrOut, "# Failed to read config, falling back to gitlab-shell-authorized-keys-check-ruby")
+		execRuby(paths.RootDir, args, readWriter)
+	}
+
+	// Only the Go implementation has a cache to invalidate
+	if !authorizedkeys.IsInvalidation(args[1:]) && !config.FeatureEnabled(feature) {
+		execRuby(paths.RootDir, args, readWriter)
+	}
+
+	logger.ProgName = "gitlab-shell-authorized-keys-check"
+	// If the log file can't be opened, messages go to syslog instead
+	logger.Configure(config)
+	logger.Info("Found the gitlab-shell paths", paths.LogFields())
+
+	cmd := &authorizedkeys.Command{Config: config, Args: args[1:], ReadWriter: readWriter}
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintln(readWriter.ErrOut, err)
+		os.Exit(1)
+	}
+}
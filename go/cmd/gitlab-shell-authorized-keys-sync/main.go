@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/keyssync"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+func main() {
+	readWriter := &readwriter.ReadWriter{
+		Out:    os.Stdout,
+		In:     os.Stdin,
+		ErrOut: os.Stderr,
+	}
+
+	flags, args, err := config.ParseFlags(os.Args)
+	if err != nil {
+		fmt.Fprintln(readWriter.ErrOut, err)
+		os.Exit(1)
+	}
+
+	paths, err := config.FindPaths(flags, config.RootDirFromExecutable)
+	if err != nil {
+		fmt.Fprintln(readWriter.ErrOut, "Failed to determine root directory, exiting")
+		os.Exit(1)
+	}
+
+	config, err := config.NewFromPaths(paths)
+	if err != nil {
+		fmt.Fprintf(readWriter.ErrOut, "Failed to read config: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.ProgName = "gitlab-shell-authorized-keys-sync"
+	// If the log file can't be opened, messages go to syslog instead
+	logger.Configure(config)
+	logger.Info("Found the gitlab-shell paths", paths.LogFields())
+
+	cmd := &keyssync.Command{Config: config, Args: args[1:], ReadWriter: readWriter}
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintln(readWriter.ErrOut, err)
+		os.Exit(1)
+	}
+}
@@ -3,31 +3,20 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/console"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
 )
 
-// findRootDir determines the root directory (and so, the location of the config
-// file) from os.Executable()
-func findRootDir() (string, error) {
-	path, err := os.Executable()
-	if err != nil {
-		return "", err
-	}
-
-	// Start: /opt/.../gitlab-shell/bin/gitlab-shell
-	// Ends:  /opt/.../gitlab-shell
-	return filepath.Dir(filepath.Dir(path)), nil
-}
-
 // rubyExec will never return. It either replaces the current process with a
 // Ruby interpreter, or outputs an error and kills the process.
-func execRuby(rootDir string, readWriter *readwriter.ReadWriter) {
-	cmd := &fallback.Command{RootDir: rootDir, Args: os.Args}
+func execRuby(rootDir string, args []string, readWriter *readwriter.ReadWriter) {
+	cmd := &fallback.Command{RootDir: rootDir, Args: args}
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(readWriter.ErrOut, "Failed to exec: %v\n", err)
@@ -35,28 +24,84 @@ func execRuby(rootDir string, readWriter *readwriter.ReadWriter) {
 	}
 }
 
+// deniedMessages returns the messages for users whose command was denied
+// before running, or nil for any other error
+func deniedMessages(err error) []string {
+	switch e := err.(type) {
+	case *commandargs.DisallowedCommandError:
+		if e.Usage != "" {
+			return []string{e.Error(), "Usage: " + e.Usage}
+		}
+
+		return []string{e.Error()}
+	case *commandargs.InvalidRepositoryPathError, *command.DeniedError:
+		return []string{e.Error()}
+	}
+
+	return nil
+}
+
+// fallBackToRuby runs the Ruby implementation instead of a Go command that
+// doesn't support what was asked, as long as the command wrote nothing yet
+func fallBackToRuby(err error, rootDir string, args []string, readWriter *readwriter.ReadWriter, out, errOut *readwriter.Recorder) {
+	unsupported, ok := err.(*fallback.UnsupportedError)
+	if !ok || out.Written || errOut.Written {
+		return
+	}
+
+	logger.Warn("Falling back to gitlab-shell-ruby", map[string]interface{}{
+		"reason": unsupported.Reason,
+	})
+
+	execRuby(rootDir, args, readWriter)
+}
+
 func main() {
+	out := &readwriter.Recorder{Writer: os.Stdout}
+	errOut := &readwriter.Recorder{Writer: os.Stderr}
 	readWriter := &readwriter.ReadWriter{
-		Out:    os.Stdout,
+		Out:    out,
 		In:     os.Stdin,
-		ErrOut: os.Stderr,
+		ErrOut: errOut,
 	}
 
-	rootDir, err := findRootDir()
+	flags, args, err := config.ParseFlags(os.Args)
+	if err != nil {
+		fmt.Fprintln(readWriter.ErrOut, err)
+		os.Exit(1)
+	}
+
+	paths, err := config.FindPaths(flags, config.RootDirFromExecutable)
 	if err != nil {
 		fmt.Fprintln(readWriter.ErrOut, "Failed to determine root directory, exiting")
 		os.Exit(1)
 	}
 
+	// The Ruby implementation must use the same paths
+	if err := paths.Export(); err != nil {
+		fmt.Fprintf(readWriter.ErrOut, "Failed to export the paths: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Fall back to Ruby in case of problems reading the config, but issue a
 	// warning as this isn't something we can sustain indefinitely
-	config, err := config.NewFromDir(rootDir)
+	config, err := config.NewFromPaths(paths)
 	if err != nil {
 		fmt.Fprintln(readWriter.ErrOut, "Failed to read config, falling back to gitlab-shell-ruby")
-		execRuby(rootDir, readWriter)
+		execRuby(paths.RootDir, args, readWriter)
+	}
+
+	logger.ProgName = "gitlab-shell"
+	// If the log file can't be opened, messages go to syslog instead
+	logger.Configure(config)
+	logger.Info("Found the gitlab-shell paths", paths.LogFields())
+
+	cmd, err := command.New(args, config, readWriter)
+	if messages := deniedMessages(err); messages != nil {
+		console.DisplayMessages(readWriter.ErrOut, messages)
+		os.Exit(1)
 	}
 
-	cmd, err := command.New(os.Args, config, readWriter)
 	if err != nil {
 		// For now this could happen if `SSH_CONNECTION` is not set on
 		// the environment
@@ -65,8 +110,11 @@ func main() {
 	}
 
 	// The command will write to STDOUT on execution or replace the current
-	// process in case of the `fallback.Command`
+	// process in case of the `fallback.Command`. Go commands that don't
+	// support what was asked are replaced by the Ruby implementation.
 	if err = cmd.Execute(); err != nil {
+		fallBackToRuby(err, paths.RootDir, args, readWriter, out, errOut)
+
 		fmt.Fprintf(readWriter.ErrOut, "%v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,155 @@
+package authorizedkeys
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/authorizedkeys"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/keyline"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/sshkey"
+)
+
+const (
+	invalidateOption    = "--invalidate"
+	invalidateAllOption = "--invalidate-all"
+)
+
+// Command prints the authorized_keys line for a key, for sshd's
+// AuthorizedKeysCommand:
+//
+//	gitlab-shell-authorized-keys-check <expected-username> <actual-username> <key>
+//
+// It also removes keys from the cache:
+//
+//	gitlab-shell-authorized-keys-check --invalidate <key>
+//	gitlab-shell-authorized-keys-check --invalidate-all
+type Command struct {
+	Config     *config.Config
+	Args       []string
+	ReadWriter *readwriter.ReadWriter
+}
+
+// IsInvalidation tells whether the command removes keys from the cache,
+// which only the Go implementation has
+func IsInvalidation(args []string) bool {
+	return len(args) > 0 && (args[0] == invalidateOption || args[0] == invalidateAllOption)
+}
+
+func (c *Command) Execute() error {
+	if IsInvalidation(c.Args) {
+		return c.invalidate()
+	}
+
+	if len(c.Args) != 3 {
+		return fmt.Errorf("# Wrong number of arguments. %d. Usage:\n"+
+			"#     gitlab-shell-authorized-keys-check <expected-username> <actual-username> <key>", len(c.Args))
+	}
+
+	expectedUsername, actualUsername, key := c.Args[0], c.Args[1], c.Args[2]
+
+	if expectedUsername == "" || actualUsername == "" {
+		return errors.New("# No username provided")
+	}
+
+	// Only check access if the requested username matches the configured
+	// username. Normally, these would both be 'git', but it can be
+	// configured by the user.
+	if expectedUsername != actualUsername {
+		return nil
+	}
+
+	if key == "" {
+		return errors.New("# No key provided")
+	}
+
+	fmt.Fprintln(c.ReadWriter.Out, c.keyLine(key))
+
+	return nil
+}
+
+func (c *Command) keyLine(key string) string {
+	notFound := fmt.Sprintf("# No key was found for %s", key)
+
+	if _, err := sshkey.ParseBlob(key); err != nil {
+		logger.Warn("Invalid key provided", map[string]interface{}{"error": err.Error()})
+		return notFound
+	}
+
+	client, err := authorizedkeys.NewClient(c.Config)
+	if err != nil {
+		logger.Error("Failed to look up the key", err)
+		return notFound
+	}
+
+	response, err := client.GetByKey(key)
+	if err != nil {
+		if !gitlabnet.IsServerError(err) {
+			return notFound
+		}
+
+		logger.Error("Failed to look up the key", err)
+
+		if line := c.findInAuthFile(key); line != nil {
+			return c.toString(line, nil, notFound)
+		}
+
+		return notFound
+	}
+
+	line, err := keyline.NewPublicKeyLine(fmt.Sprintf("%d", response.Id), response.Key, c.Config.RootDir)
+	if err != nil {
+		logger.Error("Invalid key returned by the API", err)
+		return notFound
+	}
+
+	return c.toString(line, response.ExpiresAt, notFound)
+}
+
+// toString returns the line with the configured options, or notFound when
+// they are invalid
+func (c *Command) toString(line *keyline.KeyLine, expiresAt *time.Time, notFound string) string {
+	if err := line.SetOptions(keyline.NewOptions(c.Config.KeyOptions, expiresAt)); err != nil {
+		logger.Error("Invalid authorized_keys_options", err)
+		return notFound
+	}
+
+	return line.ToString()
+}
+
+// findInAuthFile looks the key up in the auth_file maintained by
+// gitlab-keys, when the API is unavailable and the fallback is enabled
+func (c *Command) findInAuthFile(key string) *keyline.KeyLine {
+	if !c.Config.AuthFileFallback {
+		return nil
+	}
+
+	index, err := keyline.LoadIndex(c.Config.AuthFile, c.Config.RootDir)
+	if err != nil {
+		logger.Error("Failed to read the auth_file", err)
+		return nil
+	}
+
+	return index.Find(key)
+}
+
+func (c *Command) invalidate() error {
+	client, err := authorizedkeys.NewClient(c.Config)
+	if err != nil {
+		return err
+	}
+
+	if c.Args[0] == invalidateAllOption && len(c.Args) == 1 {
+		return client.InvalidateAll()
+	}
+
+	if c.Args[0] == invalidateOption && len(c.Args) == 2 {
+		return client.Invalidate(c.Args[1])
+	}
+
+	return fmt.Errorf("Usage: gitlab-shell-authorized-keys-check %s <key> | %s", invalidateOption, invalidateAllOption)
+}
@@ -0,0 +1,276 @@
+package authorizedkeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/testserver"
+)
+
+const (
+	knownKey   = "AAAAC3NzaC1lZDI1NTE5AAAAIB1WPaqcY4HOEnrYcVnLTPUT8E9tfc2ZkDWpmXdvTk8P"
+	brokenKey  = "AAAAB3NzaC1yc2EAAAADAQABAAAAgQDhI/FW0RAfp6JU9U4Eb7lDxWrvyrpdf/ehnKCJNd9ee6B5UO9JCAyuSkU7ipCgX+HLDE6oYgCziSux4XTSWyK1CISbd19oLuGQl9FAMHDQ3s2iVrT5xRoOtCXzl7SsW49G2miuNDIqsnlBptxYPFQ5J0aeHbU33/cvgsWFadbh+Q=="
+	unknownKey = "AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBPvjXnvFm4N44qwdVa+IoQGcqd/8sJFheUPQ3ThiUa/7C45UJS7AVHusIQSyJ52NpLPom4SWyvYRbWzQprxa/Xc="
+	skKey      = "AAAAGnNrLXNzaC1lZDI1NTE5QG9wZW5zc2guY29tAAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4fAAAABHNzaDo="
+)
+
+var (
+	requests = []testserver.TestRequestHandler{
+		{
+			Path: "/api/v4/internal/authorized_keys",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("key") {
+				case knownKey:
+					json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "key": "ssh-ed25519 " + knownKey + "  jane@example.com\n"})
+				case skKey:
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"id":         2,
+						"key":        "sk-ssh-ed25519@openssh.com " + skKey,
+						"expires_at": "2026-10-19T12:30:00Z",
+					})
+				case brokenKey:
+					w.WriteHeader(http.StatusInternalServerError)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		},
+	}
+)
+
+func TestExecute(t *testing.T) {
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	testCases := []struct {
+		desc           string
+		arguments      []string
+		expectedOutput string
+	}{
+		{
+			desc:           "With a known key",
+			arguments:      []string{"git", "git", knownKey},
+			expectedOutput: "command=\"/tmp/bin/gitlab-shell key-1\",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty ssh-ed25519 " + knownKey + " jane@example.com\n",
+		},
+		{
+			desc:           "With a known FIDO key",
+			arguments:      []string{"git", "git", skKey},
+			expectedOutput: "command=\"/tmp/bin/gitlab-shell key-2\",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty sk-ssh-ed25519@openssh.com " + skKey + "\n",
+		},
+		{
+			desc:           "With an unknown key",
+			arguments:      []string{"git", "git", unknownKey},
+			expectedOutput: "# No key was found for " + unknownKey + "\n",
+		},
+		{
+			desc:           "With a malformed key",
+			arguments:      []string{"git", "git", "malformed"},
+			expectedOutput: "# No key was found for malformed\n",
+		},
+		{
+			desc:           "When the API fails",
+			arguments:      []string{"git", "git", brokenKey},
+			expectedOutput: "# No key was found for " + brokenKey + "\n",
+		},
+		{
+			desc:           "When run as another user",
+			arguments:      []string{"git", "other", knownKey},
+			expectedOutput: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			cmd := &Command{
+				Config:     &config.Config{RootDir: "/tmp", GitlabUrl: url},
+				Args:       tc.arguments,
+				ReadWriter: &readwriter.ReadWriter{Out: buffer},
+			}
+
+			err := cmd.Execute()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, buffer.String())
+		})
+	}
+}
+
+func TestExecuteWithOptions(t *testing.T) {
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	expiryTime := time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC).Local().Format("20060102150405")
+
+	testCases := []struct {
+		desc           string
+		options        config.KeyOptionsConfig
+		expectedOutput string
+	}{
+		{
+			desc:           "With valid options",
+			options:        config.KeyOptionsConfig{Restrict: true, From: []string{"10.0.0.0/8"}, ExpiryTime: true},
+			expectedOutput: "command=\"/tmp/bin/gitlab-shell key-2\",restrict,from=\"10.0.0.0/8\",expiry-time=\"" + expiryTime + "\" sk-ssh-ed25519@openssh.com " + skKey + "\n",
+		},
+		{
+			desc:           "With invalid options",
+			options:        config.KeyOptionsConfig{Environment: []string{`A=b",command="/bin/sh`}},
+			expectedOutput: "# No key was found for " + skKey + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			cmd := &Command{
+				Config:     &config.Config{RootDir: "/tmp", GitlabUrl: url, KeyOptions: tc.options},
+				Args:       []string{"git", "git", skKey},
+				ReadWriter: &readwriter.ReadWriter{Out: buffer},
+			}
+
+			require.NoError(t, cmd.Execute())
+			assert.Equal(t, tc.expectedOutput, buffer.String())
+		})
+	}
+}
+
+func TestExecuteWithAuthFileFallback(t *testing.T) {
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "authorizedkeys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	authFile := filepath.Join(dir, "authorized_keys")
+	content := `command="/opt/gitlab-shell/bin/gitlab-shell key-2",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty ssh-rsa ` + brokenKey + "\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-3",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty ecdsa-sha2-nistp256 ` + unknownKey + "\n"
+	require.NoError(t, ioutil.WriteFile(authFile, []byte(content), 0600))
+
+	testCases := []struct {
+		desc           string
+		key            string
+		expectedOutput string
+	}{
+		{
+			desc:           "When the API fails",
+			key:            brokenKey,
+			expectedOutput: "command=\"/tmp/bin/gitlab-shell key-2\",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty ssh-rsa " + brokenKey + "\n",
+		},
+		{
+			desc:           "When the API doesn't know the key",
+			key:            unknownKey,
+			expectedOutput: "# No key was found for " + unknownKey + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			cmd := &Command{
+				Config:     &config.Config{RootDir: "/tmp", GitlabUrl: url, AuthFile: authFile, AuthFileFallback: true},
+				Args:       []string{"git", "git", tc.key},
+				ReadWriter: &readwriter.ReadWriter{Out: buffer},
+			}
+
+			require.NoError(t, cmd.Execute())
+			assert.Equal(t, tc.expectedOutput, buffer.String())
+		})
+	}
+}
+
+func TestFailingExecute(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		arguments     []string
+		expectedError string
+	}{
+		{
+			desc:      "With too few arguments",
+			arguments: []string{"git", "git"},
+			expectedError: "# Wrong number of arguments. 2. Usage:\n" +
+				"#     gitlab-shell-authorized-keys-check <expected-username> <actual-username> <key>",
+		},
+		{
+			desc:          "Without a username",
+			arguments:     []string{"", "git", "key"},
+			expectedError: "# No username provided",
+		},
+		{
+			desc:          "Without a key",
+			arguments:     []string{"git", "git", ""},
+			expectedError: "# No key provided",
+		},
+		{
+			desc:          "With invalid invalidation arguments",
+			arguments:     []string{"--invalidate"},
+			expectedError: "Usage: gitlab-shell-authorized-keys-check --invalidate <key> | --invalidate-all",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			cmd := &Command{
+				Config:     &config.Config{GitlabUrl: "http+unix://gitlab.socket"},
+				Args:       tc.arguments,
+				ReadWriter: &readwriter.ReadWriter{Out: buffer},
+			}
+
+			err := cmd.Execute()
+
+			assert.EqualError(t, err, tc.expectedError)
+			assert.Empty(t, buffer.String())
+		})
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "authorizedkeys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := &config.Config{
+		RootDir:             "/tmp",
+		GitlabUrl:           url,
+		CacheDir:            dir,
+		AuthorizedKeysCache: config.AuthorizedKeysCacheConfig{Enabled: true, TTLSeconds: 60},
+	}
+
+	execute := func(args ...string) {
+		cmd := &Command{Config: cfg, Args: args, ReadWriter: &readwriter.ReadWriter{Out: &bytes.Buffer{}}}
+		require.NoError(t, cmd.Execute())
+	}
+
+	for _, invalidation := range [][]string{{"--invalidate", knownKey}, {"--invalidate-all"}} {
+		execute("git", "git", knownKey)
+
+		files, err := ioutil.ReadDir(dir + "/authorized_keys")
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		execute(invalidation...)
+
+		files, err = ioutil.ReadDir(dir + "/authorized_keys")
+		require.NoError(t, err)
+		assert.Empty(t, files)
+	}
+}
@@ -1,39 +1,222 @@
 package command
 
 import (
+	"os"
+
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/discover"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/help"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/personalaccesstoken"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/twofactorrecover"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/twofactorverify"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/denylist"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
 )
 
 type Command interface {
 	Execute() error
 }
 
+// DeniedError is returned when the installation denies a command before
+// running it: commands disabled in the config, pushes in maintenance mode and
+// users on the local denylist
+type DeniedError struct {
+	Message string
+}
+
+func (e *DeniedError) Error() string {
+	return e.Message
+}
+
+type builder func(*config.Config, *commandargs.CommandArgs, *readwriter.ReadWriter) Command
+
+var (
+	// builders holds the Go implementation of every migrated command. The
+	// commands themselves are registered in commandargs.Commands.
+	builders = map[commandargs.CommandType]builder{
+		commandargs.Discover: func(config *config.Config, args *commandargs.CommandArgs, readWriter *readwriter.ReadWriter) Command {
+			return &discover.Command{Config: config, Args: args, ReadWriter: readWriter}
+		},
+		commandargs.TwoFactorRecover: func(config *config.Config, args *commandargs.CommandArgs, readWriter *readwriter.ReadWriter) Command {
+			return &twofactorrecover.Command{Config: config, Args: args, ReadWriter: readWriter}
+		},
+		commandargs.TwoFactorVerify: func(config *config.Config, args *commandargs.CommandArgs, readWriter *readwriter.ReadWriter) Command {
+			return &twofactorverify.Command{Config: config, Args: args, ReadWriter: readWriter}
+		},
+		commandargs.PersonalAccessToken: func(config *config.Config, args *commandargs.CommandArgs, readWriter *readwriter.ReadWriter) Command {
+			return &personalaccesstoken.Command{Config: config, Args: args, ReadWriter: readWriter}
+		},
+		commandargs.Help: func(config *config.Config, args *commandargs.CommandArgs, readWriter *readwriter.ReadWriter) Command {
+			return &help.Command{Config: config, Args: args, ReadWriter: readWriter, UseGo: func(featureName string) bool {
+				inRollout, _ := useGo(featureName, args, config)
+				return inRollout
+			}}
+		},
+	}
+)
+
 func New(arguments []string, config *config.Config, readWriter *readwriter.ReadWriter) (Command, error) {
 	args, err := commandargs.Parse(arguments)
 
+	// Denied users get the denial for any command, even one that isn't
+	// allowed
+	if args != nil {
+		if err := checkDenylist(args, config); err != nil {
+			return nil, err
+		}
+	}
+
 	if err != nil {
+		logParseError(err)
 		return nil, err
 	}
 
-	if config.FeatureEnabled(string(args.CommandType)) {
-		return buildCommand(args, config, readWriter), nil
+	if err := checkCommandEnabled(args, config); err != nil {
+		return nil, err
 	}
 
-	return &fallback.Command{RootDir: config.RootDir, Args: arguments}, nil
+	if err := checkReadOnly(args, config); err != nil {
+		return nil, err
+	}
+
+	if cmd := buildCommand(arguments, args, config, readWriter); cmd != nil {
+		return cmd, nil
+	}
+
+	cmd := &fallback.Command{RootDir: config.RootDir, Args: arguments}
+
+	// The Ruby implementation runs the command with the normalized
+	// repository path. Without a command, it runs discover, so
+	// SSH_ORIGINAL_COMMAND must stay unset.
+	if len(args.SshArgs) > 0 {
+		cmd.Env = map[string]string{"SSH_ORIGINAL_COMMAND": args.NormalizedCommand()}
+	}
+
+	return cmd, nil
 }
 
-func buildCommand(args *commandargs.CommandArgs, config *config.Config, readWriter *readwriter.ReadWriter) Command {
+func logParseError(err error) {
+	switch e := err.(type) {
+	case *commandargs.DisallowedCommandError:
+		logger.Warn("Denied disallowed command", map[string]interface{}{
+			"command": e.Command,
+			"user":    e.User,
+		})
+	case *commandargs.InvalidRepositoryPathError:
+		logger.Warn("Denied invalid repository path", map[string]interface{}{
+			"command": e.Command,
+			"user":    e.User,
+			"path":    e.Path,
+			"rule":    e.Rule,
+		})
+	}
+}
+
+// checkDenylist denies keys, users and networks on the local denylist. The
+// list is read on every invocation so changes apply immediately.
+func checkDenylist(args *commandargs.CommandArgs, config *config.Config) error {
+	list, errs := denylist.Load(config.Denylist.File)
+	for _, err := range errs {
+		logger.Error("Failed to read the denylist", err)
+	}
+
+	remoteIP := denylist.RemoteIP(os.Getenv("SSH_CONNECTION"))
+	entry := list.Match(args.Who.ValueFor(commandargs.WhoKey), args.Who.ValueFor(commandargs.WhoUsername), remoteIP)
+	if entry == "" {
+		return nil
+	}
+
+	logger.Warn("Denied by the local denylist", map[string]interface{}{
+		"command":   args.SshCommand,
+		"user":      args.LogUsername(),
+		"remote_ip": remoteIP,
+		"entry":     entry,
+	})
+
+	return &DeniedError{Message: config.DenylistMessage()}
+}
+
+// checkCommandEnabled denies commands the installation disabled, whether
+// they're implemented in Go or Ruby
+func checkCommandEnabled(args *commandargs.CommandArgs, config *config.Config) error {
+	commandName := string(args.CommandType)
+	if commandName == "" || config.CommandEnabled(commandName) {
+		return nil
+	}
+
+	logger.Warn("Denied disabled command", map[string]interface{}{
+		"command": args.SshCommand,
+		"user":    args.LogUsername(),
+	})
+
+	return &DeniedError{Message: config.CommandDisabledMessage(commandName)}
+}
+
+// checkReadOnly denies pushes while the host is in maintenance mode. Fetches
+// are still allowed.
+func checkReadOnly(args *commandargs.CommandArgs, config *config.Config) error {
+	if !isWrite(args) || !config.ReadOnly() {
+		return nil
+	}
+
+	logger.Warn("Denied push in maintenance mode", map[string]interface{}{
+		"command": args.SshCommand,
+		"user":    args.LogUsername(),
+	})
+
+	return &DeniedError{Message: config.ReadOnlyMessage()}
+}
+
+func isWrite(args *commandargs.CommandArgs) bool {
 	switch args.CommandType {
-	case commandargs.Discover:
-		return &discover.Command{Config: config, Args: args, ReadWriter: readWriter}
-	case commandargs.TwoFactorRecover:
-		return &twofactorrecover.Command{Config: config, Args: args, ReadWriter: readWriter}
+	case commandargs.ReceivePack:
+		return true
+	case commandargs.LfsAuthenticate:
+		return args.SshArgs[2] == "upload"
+	default:
+		return false
+	}
+}
+
+// buildCommand returns the Go implementation of the command, or nil if it
+// has none or the session isn't in the rollout of its migration feature.
+// Commands without side effects can have their Ruby implementation run in
+// their shadow.
+func buildCommand(arguments []string, args *commandargs.CommandArgs, config *config.Config, readWriter *readwriter.ReadWriter) Command {
+	commandType := args.CommandType
+
+	// Help, and the list of available commands for unknown commands, only
+	// exist in Go. The Ruby implementation has a "Disallowed command" error
+	// for both, so they don't depend on the help feature.
+	if commandType == "" {
+		logImplementation(args, goImplementation, "unknown command")
+		return builders[commandargs.Help](config, args, readWriter)
+	}
+
+	if commandType == commandargs.Help {
+		logImplementation(args, goImplementation, "help")
+		return builders[commandargs.Help](config, args, readWriter)
+	}
+
+	build, ok := builders[commandType]
+	if !ok {
+		return nil
+	}
+
+	inRollout, reason := useGo(string(commandType), args, config)
+	if !inRollout {
+		logImplementation(args, rubyImplementation, reason)
+		return nil
+	}
+
+	logImplementation(args, goImplementation, reason)
+
+	if shadowable[commandType] && config.FeatureShadowed(string(commandType)) {
+		return newShadowCommand(build, arguments, args, config, readWriter)
 	}
 
-	return nil
+	return build(config, args, readWriter)
 }
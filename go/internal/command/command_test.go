@@ -1,12 +1,22 @@
 package command
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/discover"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/help"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/personalaccesstoken"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/twofactorrecover"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/twofactorverify"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/testhelper"
 )
@@ -58,6 +68,84 @@ func TestNew(t *testing.T) {
 			},
 			expectedType: &twofactorrecover.Command{},
 		},
+		{
+			desc:      "it returns a TwoFactorVerify command if the feature is enabled",
+			arguments: []string{},
+			config: &config.Config{
+				GitlabUrl: "http+unix://gitlab.socket",
+				Migration: config.MigrationConfig{Enabled: true, Features: []string{"2fa_verify"}},
+			},
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "2fa_verify",
+			},
+			expectedType: &twofactorverify.Command{},
+		},
+		{
+			desc:      "it returns a PersonalAccessToken command if the feature is enabled",
+			arguments: []string{},
+			config: &config.Config{
+				GitlabUrl: "http+unix://gitlab.socket",
+				Migration: config.MigrationConfig{Enabled: true, Features: []string{"personal_access_token"}},
+			},
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "personal_access_token newtoken api",
+			},
+			expectedType: &personalaccesstoken.Command{},
+		},
+		{
+			desc:      "it returns a Help command even if the feature is disabled",
+			arguments: []string{},
+			config: &config.Config{
+				GitlabUrl: "http+unix://gitlab.socket",
+				Migration: config.MigrationConfig{Enabled: true},
+			},
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "help",
+			},
+			expectedType: &help.Command{},
+		},
+		{
+			desc:      "it returns a Help command for unknown commands if the feature is enabled",
+			arguments: []string{},
+			config: &config.Config{
+				GitlabUrl: "http+unix://gitlab.socket",
+				Migration: config.MigrationConfig{Enabled: true, Features: []string{"help"}},
+			},
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "unknown command",
+			},
+			expectedType: &help.Command{},
+		},
+		{
+			desc:      "it returns a Help command for unknown commands if help is disabled",
+			arguments: []string{},
+			config: &config.Config{
+				GitlabUrl: "http+unix://gitlab.socket",
+				Migration: config.MigrationConfig{Enabled: false},
+			},
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "unknown command",
+			},
+			expectedType: &help.Command{},
+		},
+		{
+			desc:      "it returns a Fallback command for commands without a Go implementation",
+			arguments: []string{},
+			config: &config.Config{
+				GitlabUrl: "http+unix://gitlab.socket",
+				Migration: config.MigrationConfig{Enabled: true, Features: []string{"git-upload-pack"}},
+			},
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "git-upload-pack group/project.git",
+			},
+			expectedType: &fallback.Command{},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -73,6 +161,37 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewWithShadow(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{
+		"SSH_CONNECTION":       "1",
+		"SSH_ORIGINAL_COMMAND": "",
+	})
+	defer restoreEnv()
+
+	cfg := &config.Config{
+		GitlabUrl: "http+unix://gitlab.socket",
+		Migration: config.MigrationConfig{Enabled: true, Features: []string{"discover", "help", "2fa_verify"}, Shadow: []string{"discover", "help", "2fa_verify"}},
+	}
+	readWriter := &readwriter.ReadWriter{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+
+	command, err := New([]string{"gitlab-shell", "key-1"}, cfg, readWriter)
+	require.NoError(t, err)
+	require.IsType(t, &shadowCommand{}, command)
+	assert.IsType(t, &discover.Command{}, command.(*shadowCommand).command)
+
+	// Commands with side effects are never shadowed
+	os.Setenv("SSH_ORIGINAL_COMMAND", "2fa_verify")
+	command, err = New([]string{"gitlab-shell", "key-1"}, cfg, readWriter)
+	require.NoError(t, err)
+	assert.IsType(t, &twofactorverify.Command{}, command)
+
+	// Neither are commands Ruby doesn't have
+	os.Setenv("SSH_ORIGINAL_COMMAND", "help")
+	command, err = New([]string{"gitlab-shell", "key-1"}, cfg, readWriter)
+	require.NoError(t, err)
+	assert.IsType(t, &help.Command{}, command)
+}
+
 func TestFailingNew(t *testing.T) {
 	t.Run("It returns an error when SSH_CONNECTION is not set", func(t *testing.T) {
 		restoreEnv := testhelper.TempEnv(map[string]string{})
@@ -82,4 +201,131 @@ func TestFailingNew(t *testing.T) {
 
 		assert.Error(t, err, "Only ssh allowed")
 	})
+
+	t.Run("It returns a DisallowedCommandError for invalid arguments", func(t *testing.T) {
+		restoreEnv := testhelper.TempEnv(map[string]string{
+			"SSH_CONNECTION":       "1",
+			"SSH_ORIGINAL_COMMAND": "git-upload-pack",
+		})
+		defer restoreEnv()
+
+		_, err := New([]string{"gitlab-shell", "key-1"}, &config.Config{}, nil)
+
+		assert.IsType(t, &commandargs.DisallowedCommandError{}, err)
+		assert.EqualError(t, err, "Disallowed command")
+	})
+
+	t.Run("It returns a DeniedError for disabled commands", func(t *testing.T) {
+		restoreEnv := testhelper.TempEnv(map[string]string{
+			"SSH_CONNECTION":       "1",
+			"SSH_ORIGINAL_COMMAND": "git-upload-archive group/project.git",
+		})
+		defer restoreEnv()
+
+		disabled := false
+		cfg := &config.Config{
+			Commands: map[string]config.CommandConfig{
+				"git-upload-archive": {Enabled: &disabled, Message: "Archives are disabled"},
+			},
+		}
+
+		_, err := New([]string{"gitlab-shell", "key-1"}, cfg, nil)
+
+		assert.Equal(t, &DeniedError{Message: "Archives are disabled"}, err)
+	})
+
+	t.Run("It returns a DeniedError for pushes in maintenance mode", func(t *testing.T) {
+		cfg := &config.Config{Maintenance: config.MaintenanceConfig{Enabled: true, Message: "Read-only"}}
+
+		for _, sshCommand := range []string{"git-receive-pack group/project.git", "git-lfs-authenticate group/project.git upload"} {
+			restoreEnv := testhelper.TempEnv(map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": sshCommand,
+			})
+
+			_, err := New([]string{"gitlab-shell", "key-1"}, cfg, nil)
+			restoreEnv()
+
+			assert.Equal(t, &DeniedError{Message: "Read-only"}, err, sshCommand)
+		}
+	})
+
+	t.Run("It allows fetches in maintenance mode", func(t *testing.T) {
+		cfg := &config.Config{Maintenance: config.MaintenanceConfig{Enabled: true}}
+
+		for _, sshCommand := range []string{"git-upload-pack group/project.git", "git-lfs-authenticate group/project.git download"} {
+			restoreEnv := testhelper.TempEnv(map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": sshCommand,
+			})
+
+			command, err := New([]string{"gitlab-shell", "key-1"}, cfg, nil)
+			restoreEnv()
+
+			assert.NoError(t, err, sshCommand)
+			assert.IsType(t, &fallback.Command{}, command, sshCommand)
+		}
+	})
+
+	t.Run("It returns a DeniedError for keys on the denylist", func(t *testing.T) {
+		restoreEnv := testhelper.TempEnv(map[string]string{
+			"SSH_CONNECTION":       "192.0.2.1 51234 10.0.0.1 22",
+			"SSH_ORIGINAL_COMMAND": "git-upload-pack group/project.git",
+		})
+		defer restoreEnv()
+
+		dir, err := ioutil.TempDir("", "denylist")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		denylistFile := filepath.Join(dir, "denylist")
+		require.NoError(t, ioutil.WriteFile(denylistFile, []byte("key-1\n198.51.100.0/24\n"), 0644))
+
+		cfg := &config.Config{Denylist: config.DenylistConfig{File: denylistFile, Message: "Blocked"}}
+
+		_, err = New([]string{"gitlab-shell", "key-1"}, cfg, nil)
+		assert.Equal(t, &DeniedError{Message: "Blocked"}, err)
+
+		_, err = New([]string{"gitlab-shell", "key-2"}, cfg, nil)
+		assert.NoError(t, err)
+
+		// Before telling whether the command is allowed
+		for _, sshCommand := range []string{"git-upload-pack", "git-upload-pack 'group/project.git", "git-upload-pack group/../../project.git"} {
+			os.Setenv("SSH_ORIGINAL_COMMAND", sshCommand)
+			_, err = New([]string{"gitlab-shell", "key-1"}, cfg, nil)
+			assert.Equal(t, &DeniedError{Message: "Blocked"}, err, sshCommand)
+		}
+
+		os.Setenv("SSH_CONNECTION", "198.51.100.7 51234 10.0.0.1 22")
+		_, err = New([]string{"gitlab-shell", "key-2"}, cfg, nil)
+		assert.Equal(t, &DeniedError{Message: "Blocked"}, err)
+	})
+}
+
+func TestNewFallbackWithNormalizedCommand(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{
+		"SSH_CONNECTION":       "1",
+		"SSH_ORIGINAL_COMMAND": "git-upload-pack /group/project/",
+	})
+	defer restoreEnv()
+
+	command, err := New([]string{"gitlab-shell", "key-1"}, &config.Config{}, nil)
+
+	require.NoError(t, err)
+	require.IsType(t, &fallback.Command{}, command)
+	assert.Equal(t, map[string]string{"SSH_ORIGINAL_COMMAND": "git-upload-pack group/project.git"}, command.(*fallback.Command).Env)
+}
+
+func TestNewFallbackForDiscover(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{
+		"SSH_CONNECTION":       "1",
+		"SSH_ORIGINAL_COMMAND": "",
+	})
+	defer restoreEnv()
+
+	command, err := New([]string{"gitlab-shell", "key-1"}, &config.Config{}, nil)
+
+	require.NoError(t, err)
+	require.IsType(t, &fallback.Command{}, command)
+	assert.Nil(t, command.(*fallback.Command).Env, "SSH_ORIGINAL_COMMAND isn't set for discover")
 }
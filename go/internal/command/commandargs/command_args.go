@@ -3,28 +3,47 @@ package commandargs
 import (
 	"errors"
 	"os"
-	"regexp"
 )
 
 type CommandType string
 
 const (
-	Discover         CommandType = "discover"
-	TwoFactorRecover CommandType = "2fa_recovery_codes"
+	Discover            CommandType = "discover"
+	TwoFactorRecover    CommandType = "2fa_recovery_codes"
+	TwoFactorVerify     CommandType = "2fa_verify"
+	PersonalAccessToken CommandType = "personal_access_token"
+	Help                CommandType = "help"
+	UploadPack          CommandType = "git-upload-pack"
+	ReceivePack         CommandType = "git-receive-pack"
+	UploadArchive       CommandType = "git-upload-archive"
+	LfsAuthenticate     CommandType = "git-lfs-authenticate"
 )
 
-var (
-	whoKeyRegex      = regexp.MustCompile(`\bkey-(?P<keyid>\d+)\b`)
-	whoUsernameRegex = regexp.MustCompile(`\busername-(?P<username>\S+)\b`)
-)
+// DisallowedCommandError is returned for commands that can't be parsed or
+// don't receive the arguments they expect, like gitlab-shell-ruby's
+// GitlabShell::DisallowedCommandError
+type DisallowedCommandError struct {
+	// Command is the original SSH command
+	Command string
+	// User describes who ran the command, for logging
+	User string
+	// Usage of the command, if it is a known one
+	Usage string
+}
+
+func (e *DisallowedCommandError) Error() string {
+	return "Disallowed command"
+}
 
 type CommandArgs struct {
-	GitlabUsername string
-	GitlabKeyId    string
-	SshCommand     string
-	CommandType    CommandType
+	Who         *Who
+	SshCommand  string
+	SshArgs     []string
+	CommandType CommandType
 }
 
+// Parse finds who runs which SSH command. When only the command is invalid,
+// the returned CommandArgs still tell who ran it, along with the error.
 func Parse(arguments []string) (*CommandArgs, error) {
 	if sshConnection := os.Getenv("SSH_CONNECTION"); sshConnection == "" {
 		return nil, errors.New("Only ssh allowed")
@@ -32,56 +51,117 @@ func Parse(arguments []string) (*CommandArgs, error) {
 
 	info := &CommandArgs{}
 
-	info.parseWho(arguments)
-	info.parseCommand(os.Getenv("SSH_ORIGINAL_COMMAND"))
+	if err := info.parseWho(arguments); err != nil {
+		return nil, err
+	}
+
+	if err := info.parseCommand(os.Getenv("SSH_ORIGINAL_COMMAND")); err != nil {
+		return info, err
+	}
 
 	return info, nil
 }
 
-func (c *CommandArgs) parseWho(arguments []string) {
+// parseWho finds the key-, user- or username- argument in authorized_keys
+func (c *CommandArgs) parseWho(arguments []string) error {
 	for _, argument := range arguments {
-		if keyId := tryParseKeyId(argument); keyId != "" {
-			c.GitlabKeyId = keyId
-			break
+		if _, ok := whoKind(argument); !ok {
+			continue
 		}
 
-		if username := tryParseUsername(argument); username != "" {
-			c.GitlabUsername = username
-			break
+		who, err := ParseWho(argument)
+		if err != nil {
+			return err
 		}
+
+		c.Who = who
+		break
 	}
+
+	return nil
 }
 
-func tryParseKeyId(argument string) string {
-	matchInfo := whoKeyRegex.FindStringSubmatch(argument)
-	if len(matchInfo) == 2 {
-		// The first element is the full matched string
-		// The second element is the named `keyid`
-		return matchInfo[1]
+// LogUsername describes the user like gitlab-shell-ruby's log_username
+func (c *CommandArgs) LogUsername() string {
+	switch {
+	case c.Who == nil:
+		return ""
+	case c.Who.Kind == WhoUsername:
+		return c.Who.Value
+	case c.Who.Kind == WhoKey:
+		return "user with key " + c.Who.String()
+	default:
+		return "user with id " + c.Who.String()
 	}
-
-	return ""
 }
 
-func tryParseUsername(argument string) string {
-	matchInfo := whoUsernameRegex.FindStringSubmatch(argument)
-	if len(matchInfo) == 2 {
-		// The first element is the full matched string
-		// The second element is the named `username`
-		return matchInfo[1]
+// NormalizedCommand returns the SSH command with its arguments as parsed,
+// e.g. with the normalized repository path, for the Ruby implementation to
+// run
+func (c *CommandArgs) NormalizedCommand() string {
+	if len(c.SshArgs) == 0 {
+		return c.SshCommand
 	}
 
-	return ""
+	return joinShellWords(c.SshArgs)
 }
 
-func (c *CommandArgs) parseCommand(commandString string) {
+func (c *CommandArgs) parseCommand(commandString string) error {
 	c.SshCommand = commandString
 
-	if commandString == "" {
+	args, err := splitShellWords(commandString)
+	if err != nil {
+		return &DisallowedCommandError{Command: commandString, User: c.LogUsername()}
+	}
+
+	if len(args) == 0 {
 		c.CommandType = Discover
+		return nil
+	}
+
+	// Git for Windows 2.14 runs `git upload-pack` instead of `git-upload-pack`
+	if len(args) == 3 && args[0] == "git" {
+		args = []string{"git-" + args[1], args[2]}
+	}
+
+	c.SshArgs = args
+
+	// Unknown commands keep an empty CommandType
+	info := Lookup(args[0])
+	if info == nil {
+		return nil
+	}
+
+	if !validArgs(info, args[1:]) {
+		return &DisallowedCommandError{Command: commandString, User: c.LogUsername(), Usage: info.GoUsage()}
 	}
 
-	if CommandType(commandString) == TwoFactorRecover {
-		c.CommandType = TwoFactorRecover
+	if info.HasRepository {
+		path, rule := normalizeRepositoryPath(args[1])
+		if rule != "" {
+			return &InvalidRepositoryPathError{Command: commandString, User: c.LogUsername(), Path: args[1], Rule: rule}
+		}
+
+		args[1] = path
 	}
+
+	c.CommandType = info.Type
+
+	return nil
+}
+
+func validArgs(info *CommandInfo, args []string) bool {
+	if len(args) < info.MinArgs || (info.MaxArgs >= 0 && len(args) > info.MaxArgs) {
+		return false
+	}
+
+	if info.Type == LfsAuthenticate {
+		switch args[1] {
+		case "download", "upload":
+		default:
+			return false
+		}
+	}
+
+	return true
 }
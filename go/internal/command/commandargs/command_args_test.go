@@ -30,7 +30,73 @@ func TestParseSuccess(t *testing.T) {
 				"SSH_CONNECTION":       "1",
 				"SSH_ORIGINAL_COMMAND": "hello world",
 			},
-			expectedArgs: &CommandArgs{SshCommand: "hello world"},
+			expectedArgs: &CommandArgs{SshCommand: "hello world", SshArgs: []string{"hello", "world"}},
+		}, {
+			desc: "It sets the command type and arguments for a known command",
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "personal_access_token newtoken api,read_user 30",
+			},
+			expectedArgs: &CommandArgs{
+				SshCommand:  "personal_access_token newtoken api,read_user 30",
+				SshArgs:     []string{"personal_access_token", "newtoken", "api,read_user", "30"},
+				CommandType: PersonalAccessToken,
+			},
+		}, {
+			desc: "It sets the command type for git commands",
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "git-receive-pack group/project.git",
+			},
+			expectedArgs: &CommandArgs{
+				SshCommand:  "git-receive-pack group/project.git",
+				SshArgs:     []string{"git-receive-pack", "group/project.git"},
+				CommandType: ReceivePack,
+			},
+		}, {
+			desc: "It unquotes the arguments",
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "git-upload-pack 'group/my project.git'",
+			},
+			expectedArgs: &CommandArgs{
+				SshCommand:  "git-upload-pack 'group/my project.git'",
+				SshArgs:     []string{"git-upload-pack", "group/my project.git"},
+				CommandType: UploadPack,
+			},
+		}, {
+			desc: "It normalizes the repository path",
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "git-upload-archive '/group/project'",
+			},
+			expectedArgs: &CommandArgs{
+				SshCommand:  "git-upload-archive '/group/project'",
+				SshArgs:     []string{"git-upload-archive", "group/project.git"},
+				CommandType: UploadArchive,
+			},
+		}, {
+			desc: "It rewrites git commands run by Git for Windows",
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "git upload-pack 'group/project.git'",
+			},
+			expectedArgs: &CommandArgs{
+				SshCommand:  "git upload-pack 'group/project.git'",
+				SshArgs:     []string{"git-upload-pack", "group/project.git"},
+				CommandType: UploadPack,
+			},
+		}, {
+			desc: "It accepts the LFS operation and extra arguments",
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "git-lfs-authenticate group/project.git download oid",
+			},
+			expectedArgs: &CommandArgs{
+				SshCommand:  "git-lfs-authenticate group/project.git download oid",
+				SshArgs:     []string{"git-lfs-authenticate", "group/project.git", "download", "oid"},
+				CommandType: LfsAuthenticate,
+			},
 		}, {
 			desc: "It finds the key id in any passed arguments",
 			environment: map[string]string{
@@ -38,7 +104,7 @@ func TestParseSuccess(t *testing.T) {
 				"SSH_ORIGINAL_COMMAND": "",
 			},
 			arguments:    []string{"hello", "key-123"},
-			expectedArgs: &CommandArgs{CommandType: Discover, GitlabKeyId: "123"},
+			expectedArgs: &CommandArgs{CommandType: Discover, Who: &Who{Kind: WhoKey, Value: "123"}},
 		}, {
 			desc: "It finds the username in any passed arguments",
 			environment: map[string]string{
@@ -46,7 +112,15 @@ func TestParseSuccess(t *testing.T) {
 				"SSH_ORIGINAL_COMMAND": "",
 			},
 			arguments:    []string{"hello", "username-jane-doe"},
-			expectedArgs: &CommandArgs{CommandType: Discover, GitlabUsername: "jane-doe"},
+			expectedArgs: &CommandArgs{CommandType: Discover, Who: &Who{Kind: WhoUsername, Value: "jane-doe"}},
+		}, {
+			desc: "It finds the user id in any passed arguments",
+			environment: map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": "",
+			},
+			arguments:    []string{"hello", "user-45"},
+			expectedArgs: &CommandArgs{CommandType: Discover, Who: &Who{Kind: WhoUser, Value: "45"}},
 		},
 	}
 
@@ -70,4 +144,105 @@ func TestParseFailure(t *testing.T) {
 		assert.Error(t, err, "Only ssh allowed")
 	})
 
+	t.Run("It fails for an invalid key id", func(t *testing.T) {
+		restoreEnv := testhelper.TempEnv(map[string]string{"SSH_CONNECTION": "1"})
+		defer restoreEnv()
+
+		_, err := Parse([]string{"gitlab-shell", "key-abc"})
+
+		assert.EqualError(t, err, "who='key-abc' is invalid!")
+	})
+
+	testCases := []struct {
+		desc          string
+		command       string
+		expectedUsage string
+	}{
+		{
+			desc:    "It fails for unmatched quotes",
+			command: "git-upload-pack 'group/project.git",
+		},
+		{
+			desc:          "It fails for git commands without a repository",
+			command:       "git-upload-pack",
+			expectedUsage: "git-upload-pack <repository>",
+		},
+		{
+			desc:          "It fails for git commands with too many arguments",
+			command:       "git-receive-pack group/project.git other",
+			expectedUsage: "git-receive-pack <repository>",
+		},
+		{
+			desc:          "It fails for LFS commands without an operation",
+			command:       "git-lfs-authenticate group/project.git",
+			expectedUsage: "git-lfs-authenticate <repository> <download|upload>",
+		},
+		{
+			desc:          "It fails for LFS commands with an unknown operation",
+			command:       "git-lfs-authenticate group/project.git delete",
+			expectedUsage: "git-lfs-authenticate <repository> <download|upload>",
+		},
+		{
+			desc:          "It fails for commands that take no arguments",
+			command:       "2fa_verify 123456",
+			expectedUsage: "2fa_verify",
+		},
+		{
+			desc:          "It fails for too many options",
+			command:       "2fa_recovery_codes --yes --format=json --verbose",
+			expectedUsage: "2fa_recovery_codes [--yes] [--format=text|json]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			restoreEnv := testhelper.TempEnv(map[string]string{
+				"SSH_CONNECTION":       "1",
+				"SSH_ORIGINAL_COMMAND": tc.command,
+			})
+			defer restoreEnv()
+
+			_, err := Parse([]string{"gitlab-shell", "key-1"})
+
+			expectedErr := &DisallowedCommandError{
+				Command: tc.command,
+				User:    "user with key key-1",
+				Usage:   tc.expectedUsage,
+			}
+			assert.Equal(t, expectedErr, err)
+			assert.EqualError(t, err, "Disallowed command")
+		})
+	}
+}
+
+func TestParseInvalidRepositoryPath(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{
+		"SSH_CONNECTION":       "1",
+		"SSH_ORIGINAL_COMMAND": "git-upload-pack 'group/../../project.git'",
+	})
+	defer restoreEnv()
+
+	_, err := Parse([]string{"gitlab-shell", "username-jane-doe"})
+
+	expectedErr := &InvalidRepositoryPathError{
+		Command: "git-upload-pack 'group/../../project.git'",
+		User:    "jane-doe",
+		Path:    "group/../../project.git",
+		Rule:    "path traversal",
+	}
+	assert.Equal(t, expectedErr, err)
+	assert.EqualError(t, err, "Invalid repository path")
+}
+
+func TestNormalizedCommand(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{
+		"SSH_CONNECTION":       "1",
+		"SSH_ORIGINAL_COMMAND": "git upload-pack '/group//my project/'",
+	})
+	defer restoreEnv()
+
+	args, err := Parse([]string{"key-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "git-upload-pack 'group/my project.git'", args.NormalizedCommand())
 }
@@ -0,0 +1,118 @@
+package commandargs
+
+// CommandInfo describes a command users can run over SSH
+type CommandInfo struct {
+	Type        CommandType
+	Usage       string
+	Description string
+	// MinArgs and MaxArgs bound the number of arguments following the
+	// command name. A negative MaxArgs means there is no upper bound.
+	MinArgs int
+	MaxArgs int
+	// HasRepository commands take a repository path as their first argument
+	HasRepository bool
+	// RubyImplemented commands are available through gitlab-shell-ruby, even
+	// when their Go migration feature is disabled
+	RubyImplemented bool
+	// GoOptions are the options only the Go implementation supports. The
+	// Ruby one ignores them.
+	GoOptions string
+}
+
+// Commands is the registry of every command gitlab-shell accepts, in the
+// order they are listed to users
+var Commands = []*CommandInfo{
+	{
+		Type:            Discover,
+		Usage:           "",
+		Description:     "Show which user you are authenticated as",
+		RubyImplemented: true,
+	},
+	{
+		Type:            TwoFactorRecover,
+		Usage:           "2fa_recovery_codes",
+		MinArgs:         0,
+		MaxArgs:         2,
+		Description:     "Generate new two-factor recovery codes",
+		RubyImplemented: true,
+		GoOptions:       "[--yes] [--format=text|json]",
+	},
+	{
+		Type:        TwoFactorVerify,
+		Usage:       "2fa_verify",
+		MinArgs:     0,
+		MaxArgs:     0,
+		Description: "Verify a two-factor one-time password",
+	},
+	{
+		Type:        PersonalAccessToken,
+		Usage:       "personal_access_token <name> <scope1[,scope2,...]> [ttl_days]",
+		MinArgs:     2,
+		MaxArgs:     3,
+		Description: "Create a personal access token",
+	},
+	{
+		Type:            UploadPack,
+		Usage:           "git-upload-pack <repository>",
+		MinArgs:         1,
+		MaxArgs:         1,
+		HasRepository:   true,
+		Description:     "Fetch from a repository (used by git fetch, pull and clone)",
+		RubyImplemented: true,
+	},
+	{
+		Type:            ReceivePack,
+		Usage:           "git-receive-pack <repository>",
+		MinArgs:         1,
+		MaxArgs:         1,
+		HasRepository:   true,
+		Description:     "Push to a repository (used by git push)",
+		RubyImplemented: true,
+	},
+	{
+		Type:            UploadArchive,
+		Usage:           "git-upload-archive <repository>",
+		MinArgs:         1,
+		MaxArgs:         1,
+		HasRepository:   true,
+		Description:     "Download an archive of a repository (used by git archive)",
+		RubyImplemented: true,
+	},
+	{
+		Type:            LfsAuthenticate,
+		Usage:           "git-lfs-authenticate <repository> <download|upload>",
+		MinArgs:         2,
+		MaxArgs:         -1,
+		HasRepository:   true,
+		Description:     "Authenticate a Git LFS transfer",
+		RubyImplemented: true,
+	},
+	{
+		Type:        Help,
+		Usage:       "help",
+		MinArgs:     0,
+		MaxArgs:     0,
+		Description: "List the available commands",
+	},
+}
+
+// GoUsage returns the usage of the Go implementation of the command
+func (info *CommandInfo) GoUsage() string {
+	if info.GoOptions == "" {
+		return info.Usage
+	}
+
+	return info.Usage + " " + info.GoOptions
+}
+
+// Lookup returns the registered command named name, or nil if there is none.
+// The discover command has no name and can't be looked up.
+func Lookup(name string) *CommandInfo {
+	for _, info := range Commands {
+		if info.Type != Discover && string(info.Type) == name {
+			return info
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package commandargs
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxRepositoryPathLength is far beyond any real namespace and project
+	// path, so only abuse is rejected
+	maxRepositoryPathLength = 1024
+
+	repositorySuffix = ".git"
+)
+
+// InvalidRepositoryPathError is returned for repository arguments that can't
+// refer to a GitLab repository, like gitlab-shell-ruby's
+// GitlabShell::InvalidRepositoryPathError
+type InvalidRepositoryPathError struct {
+	// Command is the original SSH command
+	Command string
+	// User describes who ran the command, for logging
+	User string
+	Path string
+	// Rule is the check the path failed, for logging
+	Rule string
+}
+
+func (e *InvalidRepositoryPathError) Error() string {
+	return "Invalid repository path"
+}
+
+// normalizeRepositoryPath returns the path in the form the internal API
+// expects, `namespace/project.git`. Clients send the path as written in the
+// remote URL, so leading and trailing slashes, `~/`, empty and `.` segments
+// and a missing `.git` suffix are all accepted. It returns the rule the path
+// failed if it is invalid.
+func normalizeRepositoryPath(path string) (string, string) {
+	if len(path) > maxRepositoryPathLength {
+		return "", "too long"
+	}
+
+	for _, char := range path {
+		if char == 0 {
+			return "", "NUL byte"
+		}
+
+		if unicode.IsControl(char) {
+			return "", "control character"
+		}
+	}
+
+	path = strings.TrimPrefix(path, "~")
+
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case "", ".":
+			continue
+		case "..":
+			return "", "path traversal"
+		}
+
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 || strings.TrimSuffix(segments[len(segments)-1], repositorySuffix) == "" {
+		return "", "empty"
+	}
+
+	path = strings.Join(segments, "/")
+
+	if !strings.HasSuffix(path, repositorySuffix) {
+		path += repositorySuffix
+	}
+
+	return path, ""
+}
@@ -0,0 +1,129 @@
+package commandargs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRepositoryPath(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		path     string
+		expected string
+	}{
+		{
+			desc:     "A path with the .git suffix",
+			path:     "group/project.git",
+			expected: "group/project.git",
+		},
+		{
+			desc:     "A path without the .git suffix",
+			path:     "group/project",
+			expected: "group/project.git",
+		},
+		{
+			desc:     "A path with leading slashes",
+			path:     "//group/project.git",
+			expected: "group/project.git",
+		},
+		{
+			desc:     "A path relative to the home directory",
+			path:     "~/group/project.git",
+			expected: "group/project.git",
+		},
+		{
+			desc:     "A wiki path",
+			path:     "/group/project.wiki",
+			expected: "group/project.wiki.git",
+		},
+		{
+			desc:     "A path with a trailing slash",
+			path:     "group/project.git/",
+			expected: "group/project.git",
+		},
+		{
+			desc:     "A path with a trailing slash and without the .git suffix",
+			path:     "group/project/",
+			expected: "group/project.git",
+		},
+		{
+			desc:     "A path with an empty segment",
+			path:     "group//project",
+			expected: "group/project.git",
+		},
+		{
+			desc:     "A path with a . segment",
+			path:     "group/./project",
+			expected: "group/project.git",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, rule := normalizeRepositoryPath(tc.path)
+
+			assert.Empty(t, rule)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestNormalizeRepositoryPathFailure(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		path         string
+		expectedRule string
+	}{
+		{
+			desc:         "A path that is too long",
+			path:         strings.Repeat("a", maxRepositoryPathLength+1),
+			expectedRule: "too long",
+		},
+		{
+			desc:         "A path with a NUL byte",
+			path:         "group/project\x00.git",
+			expectedRule: "NUL byte",
+		},
+		{
+			desc:         "A path with a control character",
+			path:         "group/project\n.git",
+			expectedRule: "control character",
+		},
+		{
+			desc:         "A path traversing to the parent directory",
+			path:         "group/../../etc/passwd",
+			expectedRule: "path traversal",
+		},
+		{
+			desc:         "A path starting with the parent directory",
+			path:         "/../project.git",
+			expectedRule: "path traversal",
+		},
+		{
+			desc:         "An empty path",
+			path:         "/.git",
+			expectedRule: "empty",
+		},
+		{
+			desc:         "A path with only slashes and . segments",
+			path:         "/./",
+			expectedRule: "empty",
+		},
+		{
+			desc:         "A path with an empty project name",
+			path:         "group/.git",
+			expectedRule: "empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, rule := normalizeRepositoryPath(tc.path)
+
+			assert.Equal(t, tc.expectedRule, rule)
+			assert.Empty(t, result)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package commandargs
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// safeShellChars don't need quoting
+	safeShellChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-.,:+/@%="
+)
+
+var (
+	errUnmatchedQuote = errors.New("Unmatched quote")
+)
+
+// splitShellWords splits a command line into words the way a POSIX shell
+// would, like Ruby's Shellwords.split:
+//
+//   - words are separated by unquoted whitespace
+//   - single quotes preserve everything up to the next single quote
+//   - double quotes preserve everything up to the next double quote, except
+//     that a backslash escapes $, `, ", \ and newline
+//   - outside quotes a backslash escapes any character
+func splitShellWords(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		escaped bool
+		quote   rune
+	)
+
+	for _, char := range line {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", char) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				word.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inWord = true
+		case isShellSpace(char):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(char)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, errUnmatchedQuote
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// joinShellWords is the reverse of splitShellWords, like Ruby's
+// Shellwords.join. Words with characters a shell would interpret are single
+// quoted.
+func joinShellWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = quoteShellWord(word)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func quoteShellWord(word string) string {
+	if word != "" && strings.Trim(word, safeShellChars) == "" {
+		return word
+	}
+
+	return "'" + strings.Replace(word, "'", `'\''`, -1) + "'"
+}
+
+func isShellSpace(char rune) bool {
+	return char == ' ' || char == '\t' || char == '\n'
+}
@@ -0,0 +1,110 @@
+package commandargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		line     string
+		expected []string
+	}{
+		{
+			desc:     "An empty line",
+			line:     "",
+			expected: nil,
+		},
+		{
+			desc:     "Words separated by whitespace",
+			line:     "  git-upload-pack \t group/project.git ",
+			expected: []string{"git-upload-pack", "group/project.git"},
+		},
+		{
+			desc:     "Single quotes",
+			line:     `git-upload-pack 'group/my project.git'`,
+			expected: []string{"git-upload-pack", "group/my project.git"},
+		},
+		{
+			desc:     "Backslashes in single quotes",
+			line:     `echo 'a\b'`,
+			expected: []string{"echo", `a\b`},
+		},
+		{
+			desc:     "Double quotes with escapes",
+			line:     `echo "a \"b\" \$c \d"`,
+			expected: []string{"echo", `a "b" $c \d`},
+		},
+		{
+			desc:     "Escapes outside quotes",
+			line:     `echo a\ b \'c`,
+			expected: []string{"echo", "a b", "'c"},
+		},
+		{
+			desc:     "Quotes in the middle of a word",
+			line:     `git-upload-pack group/'pro'"ject".git`,
+			expected: []string{"git-upload-pack", "group/project.git"},
+		},
+		{
+			desc:     "Empty quotes",
+			line:     `echo '' ""`,
+			expected: []string{"echo", "", ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := splitShellWords(tc.line)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestSplitShellWordsFailure(t *testing.T) {
+	for _, line := range []string{`echo 'a`, `echo "a`, `echo a\`} {
+		t.Run(line, func(t *testing.T) {
+			_, err := splitShellWords(line)
+
+			assert.Equal(t, errUnmatchedQuote, err)
+		})
+	}
+}
+
+func TestJoinShellWords(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		words    []string
+		expected string
+	}{
+		{
+			desc:     "Words without special characters",
+			words:    []string{"git-upload-pack", "group/project.git"},
+			expected: "git-upload-pack group/project.git",
+		},
+		{
+			desc:     "Words with whitespace and quotes",
+			words:    []string{"git-upload-pack", "group/it's a project.git"},
+			expected: `git-upload-pack 'group/it'\''s a project.git'`,
+		},
+		{
+			desc:     "An empty word",
+			words:    []string{"git-upload-pack", ""},
+			expected: "git-upload-pack ''",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			line := joinShellWords(tc.words)
+			assert.Equal(t, tc.expected, line)
+
+			words, err := splitShellWords(line)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.words, words)
+		})
+	}
+}
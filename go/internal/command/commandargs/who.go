@@ -0,0 +1,91 @@
+package commandargs
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type WhoKind string
+
+const (
+	WhoKey      WhoKind = "key"
+	WhoUser     WhoKind = "user"
+	WhoUsername WhoKind = "username"
+)
+
+var (
+	whoKinds   = []WhoKind{WhoKey, WhoUser, WhoUsername}
+	whoIdRegex = regexp.MustCompile(`\A[0-9]+\z`)
+)
+
+// Who identifies the GitLab user running a command, from the key-<id>,
+// user-<id> or username-<username> argument in authorized_keys
+type Who struct {
+	Kind  WhoKind
+	Value string
+}
+
+// ParseWho parses an argument like gitlab-shell-ruby's GitlabNet.parse_who
+func ParseWho(who string) (*Who, error) {
+	kind, ok := whoKind(who)
+	if !ok {
+		return nil, invalidWhoError(who)
+	}
+
+	value := strings.TrimPrefix(who, string(kind)+"-")
+
+	switch {
+	case kind == WhoUsername && value == "":
+		return nil, invalidWhoError(who)
+	case kind != WhoUsername && !whoIdRegex.MatchString(value):
+		return nil, invalidWhoError(who)
+	}
+
+	return &Who{Kind: kind, Value: value}, nil
+}
+
+func whoKind(who string) (WhoKind, bool) {
+	for _, kind := range whoKinds {
+		if strings.HasPrefix(who, string(kind)+"-") {
+			return kind, true
+		}
+	}
+
+	return "", false
+}
+
+func invalidWhoError(who string) error {
+	return fmt.Errorf("who='%s' is invalid!", who)
+}
+
+// String returns the who as it appears in authorized_keys, e.g. key-1
+func (w *Who) String() string {
+	return fmt.Sprintf("%s-%s", w.Kind, w.Value)
+}
+
+// ValueFor returns the value if the who is of the kind, and an empty string
+// otherwise, including when there is no who
+func (w *Who) ValueFor(kind WhoKind) string {
+	if w == nil || w.Kind != kind {
+		return ""
+	}
+
+	return w.Value
+}
+
+// ParamName is the name of the internal API parameter identifying the user:
+// key_id, user_id or username
+func (w *Who) ParamName() string {
+	if w.Kind == WhoUsername {
+		return "username"
+	}
+
+	return string(w.Kind) + "_id"
+}
+
+// Params returns the query parameters identifying the user
+func (w *Who) Params() url.Values {
+	return url.Values{w.ParamName(): {w.Value}}
+}
@@ -0,0 +1,65 @@
+package commandargs
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWho(t *testing.T) {
+	testCases := []struct {
+		who               string
+		expectedWho       *Who
+		expectedParamName string
+	}{
+		{
+			who:               "key-123",
+			expectedWho:       &Who{Kind: WhoKey, Value: "123"},
+			expectedParamName: "key_id",
+		},
+		{
+			who:               "user-45",
+			expectedWho:       &Who{Kind: WhoUser, Value: "45"},
+			expectedParamName: "user_id",
+		},
+		{
+			who:               "username-jane-doe",
+			expectedWho:       &Who{Kind: WhoUsername, Value: "jane-doe"},
+			expectedParamName: "username",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.who, func(t *testing.T) {
+			who, err := ParseWho(tc.who)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedWho, who)
+			assert.Equal(t, tc.who, who.String())
+			assert.Equal(t, tc.expectedParamName, who.ParamName())
+			assert.Equal(t, url.Values{tc.expectedParamName: {tc.expectedWho.Value}}, who.Params())
+		})
+	}
+}
+
+func TestFailingParseWho(t *testing.T) {
+	for _, who := range []string{"", "key-", "key-abc", "key-1a", "user-", "user--1", "username-", "deploy-key-1"} {
+		t.Run(who, func(t *testing.T) {
+			_, err := ParseWho(who)
+
+			assert.EqualError(t, err, "who='"+who+"' is invalid!")
+		})
+	}
+}
+
+func TestValueFor(t *testing.T) {
+	who := &Who{Kind: WhoKey, Value: "1"}
+
+	assert.Equal(t, "1", who.ValueFor(WhoKey))
+	assert.Empty(t, who.ValueFor(WhoUsername))
+
+	who = nil
+	assert.Empty(t, who.ValueFor(WhoKey))
+}
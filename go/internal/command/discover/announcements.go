@@ -0,0 +1,119 @@
+package discover
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/diskcache"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/broadcastmessage"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+const (
+	announcementsCacheKey = "announcements"
+)
+
+// announcements returns the broadcast messages and the MOTD to show after the
+// welcome line. They're cached on disk so they don't cost an API call on
+// every connection. Failures are logged but never fail the command.
+func (c *Command) announcements() []string {
+	cache := &diskcache.Cache{Dir: c.Config.CacheDir, TTL: c.Config.AnnouncementsTTL()}
+	useCache := c.Config.CacheDir != ""
+
+	var cached []string
+	var haveCached bool
+	if useCache {
+		if data, fresh, ok := cache.Get(announcementsCacheKey); ok && json.Unmarshal(data, &cached) == nil {
+			if fresh {
+				return cached
+			}
+
+			haveCached = true
+		}
+	}
+
+	announcements, err := c.fetchAnnouncements()
+	if err != nil {
+		logger.Error("Failed to get broadcast messages", err)
+
+		// Rather show outdated messages than none at all
+		if haveCached {
+			return cached
+		}
+
+		return announcements
+	}
+
+	if useCache {
+		data, _ := json.Marshal(announcements)
+		if err := cache.Set(announcementsCacheKey, data); err != nil {
+			logger.Error("Failed to cache announcements", err)
+		}
+	}
+
+	return announcements
+}
+
+// WithoutAnnouncements returns the output of the command up to the
+// announcements, which gitlab-shell-ruby doesn't show. Each announcement
+// follows a blank line.
+func WithoutAnnouncements(output []byte) []byte {
+	if i := bytes.Index(output, []byte("\n\n")); i >= 0 {
+		return output[:i+1]
+	}
+
+	return output
+}
+
+func (c *Command) fetchAnnouncements() ([]string, error) {
+	announcements, err := c.getBroadcastMessages()
+
+	if motd := c.readMotd(); motd != "" {
+		announcements = append(announcements, motd)
+	}
+
+	return announcements, err
+}
+
+func (c *Command) getBroadcastMessages() ([]string, error) {
+	if !c.Config.BroadcastMessagesEnabled() {
+		return nil, nil
+	}
+
+	client, err := broadcastmessage.NewClient(c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := client.GetActiveMessages()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, message := range messages {
+		result = append(result, message.Message)
+	}
+
+	return result, nil
+}
+
+func (c *Command) readMotd() string {
+	if c.Config.Announcements.MotdFile == "" {
+		return ""
+	}
+
+	motd, err := ioutil.ReadFile(c.Config.Announcements.MotdFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Failed to read the MOTD file", err)
+		}
+
+		return ""
+	}
+
+	return strings.TrimSpace(string(motd))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/discover"
@@ -18,6 +19,10 @@ type Command struct {
 func (c *Command) Execute() error {
 	response, err := c.getUserInfo()
 	if err != nil {
+		if unsupported := fallback.UnsupportedResponse(err); unsupported != nil {
+			return unsupported
+		}
+
 		return fmt.Errorf("Failed to get username: %v", err)
 	}
 
@@ -27,6 +32,10 @@ func (c *Command) Execute() error {
 		fmt.Fprintf(c.ReadWriter.Out, "Welcome to GitLab, @%s!\n", response.Username)
 	}
 
+	for _, announcement := range c.announcements() {
+		fmt.Fprintf(c.ReadWriter.Out, "\n%s\n", announcement)
+	}
+
 	return nil
 }
 
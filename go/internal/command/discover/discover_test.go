@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,11 +39,22 @@ var (
 					json.NewEncoder(w).Encode(body)
 				} else if r.URL.Query().Get("username") == "broken" {
 					w.WriteHeader(http.StatusInternalServerError)
+				} else if r.URL.Query().Get("username") == "broken_json" {
+					fmt.Fprint(w, "{ \"username\": ")
+				} else if r.URL.Query().Get("username") == "custom_action" {
+					w.WriteHeader(http.StatusMultipleChoices)
+					fmt.Fprint(w, "{}")
 				} else {
 					fmt.Fprint(w, "null")
 				}
 			},
 		},
+		{
+			Path: "/api/v4/internal/broadcast_message",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "{}")
+			},
+		},
 	}
 )
 
@@ -56,22 +70,22 @@ func TestExecute(t *testing.T) {
 	}{
 		{
 			desc:           "With a known username",
-			arguments:      &commandargs.CommandArgs{GitlabUsername: "alex-doe"},
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoUsername, Value: "alex-doe"}},
 			expectedOutput: "Welcome to GitLab, @alex-doe!\n",
 		},
 		{
 			desc:           "With a known key id",
-			arguments:      &commandargs.CommandArgs{GitlabKeyId: "1"},
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
 			expectedOutput: "Welcome to GitLab, @alex-doe!\n",
 		},
 		{
 			desc:           "With an unknown key",
-			arguments:      &commandargs.CommandArgs{GitlabKeyId: "-1"},
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "-1"}},
 			expectedOutput: "Welcome to GitLab, Anonymous!\n",
 		},
 		{
 			desc:           "With an unknown username",
-			arguments:      &commandargs.CommandArgs{GitlabUsername: "unknown"},
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoUsername, Value: "unknown"}},
 			expectedOutput: "Welcome to GitLab, Anonymous!\n",
 		},
 	}
@@ -110,14 +124,24 @@ func TestFailingExecute(t *testing.T) {
 		},
 		{
 			desc:          "When the API returns an error",
-			arguments:     &commandargs.CommandArgs{GitlabUsername: "broken_message"},
+			arguments:     &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoUsername, Value: "broken_message"}},
 			expectedError: "Failed to get username: Forbidden!",
 		},
 		{
 			desc:          "When the API fails",
-			arguments:     &commandargs.CommandArgs{GitlabUsername: "broken"},
+			arguments:     &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoUsername, Value: "broken"}},
 			expectedError: "Failed to get username: Internal API error (500)",
 		},
+		{
+			desc:          "When the API response can't be parsed",
+			arguments:     &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoUsername, Value: "broken_json"}},
+			expectedError: "Not supported by gitlab-shell: unexpected internal API response",
+		},
+		{
+			desc:          "When the API asks for a custom action",
+			arguments:     &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoUsername, Value: "custom_action"}},
+			expectedError: "Not supported by gitlab-shell: custom action",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -136,3 +160,92 @@ func TestFailingExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteWithAnnouncements(t *testing.T) {
+	broadcastRequests := 0
+	broadcastStatus := http.StatusOK
+	requests := []testserver.TestRequestHandler{
+		requests[0],
+		{
+			Path: "/api/v4/internal/broadcast_message",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				broadcastRequests++
+				w.WriteHeader(broadcastStatus)
+				fmt.Fprint(w, `{"message": "Maintenance tonight"}`)
+			},
+		},
+	}
+
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "announcements")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	motdFile := filepath.Join(dir, "motd")
+	require.NoError(t, ioutil.WriteFile(motdFile, []byte("Be nice\n"), 0644))
+
+	cfg := &config.Config{
+		GitlabUrl: url,
+		CacheDir:  filepath.Join(dir, "cache"),
+		Announcements: config.AnnouncementsConfig{
+			MotdFile:        motdFile,
+			CacheTTLSeconds: 60,
+		},
+	}
+	expectedOutput := "Welcome to GitLab, @alex-doe!\n\nMaintenance tonight\n\nBe nice\n"
+
+	execute := func() string {
+		buffer := &bytes.Buffer{}
+		cmd := &Command{
+			Config:     cfg,
+			Args:       &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
+			ReadWriter: &readwriter.ReadWriter{Out: buffer},
+		}
+
+		require.NoError(t, cmd.Execute())
+
+		return buffer.String()
+	}
+
+	assert.Equal(t, expectedOutput, execute())
+	assert.Equal(t, expectedOutput, execute())
+	assert.Equal(t, 1, broadcastRequests, "the second execution uses the cache")
+
+	// An expired cache is still used when the API fails
+	cfg.Announcements.CacheTTLSeconds = 0
+	broadcastStatus = http.StatusInternalServerError
+
+	assert.Equal(t, expectedOutput, execute())
+	assert.Equal(t, 2, broadcastRequests)
+}
+
+func TestWithoutAnnouncements(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		output         string
+		expectedOutput string
+	}{
+		{
+			desc:           "Without announcements",
+			output:         "Welcome to GitLab, @alex-doe!\n",
+			expectedOutput: "Welcome to GitLab, @alex-doe!\n",
+		},
+		{
+			desc:           "With announcements",
+			output:         "Welcome to GitLab, @alex-doe!\n\nMaintenance tonight\n\nWelcome to\nour server\n",
+			expectedOutput: "Welcome to GitLab, @alex-doe!\n",
+		},
+		{
+			desc: "Without output",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, string(WithoutAnnouncements([]byte(tc.output))))
+		})
+	}
+}
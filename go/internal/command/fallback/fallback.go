@@ -1,14 +1,29 @@
 package fallback
 
 import (
+	"bytes"
+	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"syscall"
 )
 
 type Command struct {
 	RootDir string
 	Args    []string
+	// Program is the Ruby executable in bin/, RubyProgram by default
+	Program string
+	// Env overrides variables of the environment
+	Env map[string]string
+}
+
+// Result is the captured output of the Ruby program
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
 }
 
 var (
@@ -21,10 +36,75 @@ const (
 )
 
 func (c *Command) Execute() error {
-	rubyCmd := filepath.Join(c.RootDir, "bin", RubyProgram)
+	rubyCmd, rubyArgs := c.rubyCommand()
+
+	return execFunc(rubyCmd, rubyArgs, c.environ())
+}
+
+// Capture runs the Ruby program in a child process instead of replacing the
+// current one, without input, and returns its output. The program is killed
+// when the context is done.
+func (c *Command) Capture(ctx context.Context) (*Result, error) {
+	rubyCmd, rubyArgs := c.rubyCommand()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, rubyCmd, rubyArgs[1:]...)
+	cmd.Env = c.environ()
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	result := &Result{}
+	if err := cmd.Run(); err != nil {
+		// A killed program has no result worth returning
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, err
+		}
+
+		result.ExitCode = exitErr.Sys().(syscall.WaitStatus).ExitStatus()
+	}
+
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
 
-	// Ensure rubyArgs[0] is the full path to gitlab-shell-ruby
+	return result, nil
+}
+
+func (c *Command) rubyCommand() (string, []string) {
+	program := c.Program
+	if program == "" {
+		program = RubyProgram
+	}
+
+	rubyCmd := filepath.Join(c.RootDir, "bin", program)
+
+	// Ensure rubyArgs[0] is the full path to the Ruby program
 	rubyArgs := append([]string{rubyCmd}, c.Args[1:]...)
 
-	return execFunc(rubyCmd, rubyArgs, os.Environ())
+	return rubyCmd, rubyArgs
+}
+
+func (c *Command) environ() []string {
+	env := os.Environ()
+	if len(c.Env) == 0 {
+		return env
+	}
+
+	var result []string
+	for _, variable := range env {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if _, ok := c.Env[name]; !ok {
+			result = append(result, variable)
+		}
+	}
+
+	for name, value := range c.Env {
+		result = append(result, name+"="+value)
+	}
+
+	return result
 }
@@ -1,11 +1,17 @@
 package fallback
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/testhelper"
 )
 
 type fakeExec struct {
@@ -56,6 +62,34 @@ func TestExecuteExecsCommandSuccesfully(t *testing.T) {
 	require.Equal(t, fake.Env, os.Environ())
 }
 
+func TestExecuteExecsProgram(t *testing.T) {
+	cmd := &Command{RootDir: "/tmp", Args: fakeArgs, Program: "gitlab-shell-authorized-keys-check-ruby"}
+
+	fake := &fakeExec{}
+	fake.Setup()
+	defer fake.Cleanup()
+
+	require.NoError(t, cmd.Execute())
+	require.Equal(t, fake.Filename, "/tmp/bin/gitlab-shell-authorized-keys-check-ruby")
+	require.Equal(t, fake.Args, []string{"/tmp/bin/gitlab-shell-authorized-keys-check-ruby", "foo", "bar"})
+}
+
+func TestExecuteOverridesEnv(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{"SSH_ORIGINAL_COMMAND": "git-upload-pack /group/project"})
+	defer restoreEnv()
+
+	cmd := &Command{RootDir: "/tmp", Args: fakeArgs, Env: map[string]string{"SSH_ORIGINAL_COMMAND": "git-upload-pack group/project.git"}}
+
+	fake := &fakeExec{}
+	fake.Setup()
+	defer fake.Cleanup()
+
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, fake.Env, "SSH_ORIGINAL_COMMAND=git-upload-pack group/project.git")
+	require.NotContains(t, fake.Env, "SSH_ORIGINAL_COMMAND=git-upload-pack /group/project")
+	require.Len(t, fake.Env, len(os.Environ()))
+}
+
 func TestExecuteExecsCommandOnError(t *testing.T) {
 	cmd := &Command{RootDir: "/test", Args: fakeArgs}
 
@@ -73,3 +107,47 @@ func TestExecuteGivenNonexistentCommand(t *testing.T) {
 
 	require.Error(t, cmd.Execute())
 }
+
+func TestCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fallback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "bin"), 0755))
+	script := "#!/bin/sh\necho \"out $@\"\necho \"err $FALLBACK_TEST\" >&2\nexit 3\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bin", RubyProgram), []byte(script), 0755))
+
+	restoreEnv := testhelper.TempEnv(map[string]string{"FALLBACK_TEST": "env"})
+	defer restoreEnv()
+
+	cmd := &Command{RootDir: dir, Args: fakeArgs}
+	result, err := cmd.Capture(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, &Result{Stdout: []byte("out foo bar\n"), Stderr: []byte("err env\n"), ExitCode: 3}, result)
+}
+
+func TestCaptureGivenNonexistentCommand(t *testing.T) {
+	cmd := &Command{RootDir: "/tmp/does/not/exist", Args: fakeArgs}
+
+	_, err := cmd.Capture(context.Background())
+	require.Error(t, err)
+}
+
+func TestCaptureGivenExpiredContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fallback")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "bin"), 0755))
+	script := "#!/bin/sh\nexec sleep 10\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bin", RubyProgram), []byte(script), 0755))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	cmd := &Command{RootDir: dir, Args: fakeArgs}
+	_, err = cmd.Capture(ctx)
+
+	require.Equal(t, context.DeadlineExceeded, err)
+}
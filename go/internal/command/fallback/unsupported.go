@@ -0,0 +1,41 @@
+package fallback
+
+import (
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
+)
+
+// UnsupportedError is returned by Go commands for cases only the Ruby
+// implementation handles. Unless the command wrote output already, main runs
+// the Ruby implementation instead, so enabling a migration feature is never
+// riskier than leaving it off.
+type UnsupportedError struct {
+	Reason string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "Not supported by gitlab-shell: " + e.Reason
+}
+
+// UnsupportedResponse returns an UnsupportedError when err means the internal
+// API responded in a way the Go implementation doesn't handle: a response it
+// can't parse, or a custom action. It returns nil for any other error. Only
+// use it for requests without side effects, as the Ruby implementation
+// repeats them.
+func UnsupportedResponse(err error) error {
+	if err == gitlabnet.ParsingError {
+		return &UnsupportedError{Reason: "unexpected internal API response"}
+	}
+
+	return CustomAction(err)
+}
+
+// CustomAction returns an UnsupportedError when err is a custom action, which
+// only the Ruby implementation can carry out, or nil otherwise. The internal
+// API handled nothing in that case, so any request can be repeated.
+func CustomAction(err error) error {
+	if gitlabnet.IsCustomAction(err) {
+		return &UnsupportedError{Reason: "custom action"}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package fallback
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
+)
+
+func TestUnsupportedResponse(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		err           error
+		expectedError string
+	}{
+		{
+			desc:          "A response that can't be parsed",
+			err:           gitlabnet.ParsingError,
+			expectedError: "Not supported by gitlab-shell: unexpected internal API response",
+		},
+		{
+			desc:          "A custom action",
+			err:           &gitlabnet.ApiError{StatusCode: http.StatusMultipleChoices},
+			expectedError: "Not supported by gitlab-shell: custom action",
+		},
+		{
+			desc: "An error response",
+			err:  &gitlabnet.ApiError{StatusCode: http.StatusForbidden, Msg: "Forbidden!"},
+		},
+		{
+			desc: "Any other error",
+			err:  errors.New("Internal API unreachable"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := UnsupportedResponse(tc.err)
+
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.IsType(t, &UnsupportedError{}, err)
+				require.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestCustomAction(t *testing.T) {
+	require.NoError(t, CustomAction(gitlabnet.ParsingError))
+	require.EqualError(t, CustomAction(&gitlabnet.ApiError{StatusCode: http.StatusMultipleChoices}), "Not supported by gitlab-shell: custom action")
+}
@@ -0,0 +1,72 @@
+package help
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+type Command struct {
+	Config     *config.Config
+	Args       *commandargs.CommandArgs
+	ReadWriter *readwriter.ReadWriter
+	// UseGo tells whether the session runs the Go implementation of the
+	// command with the feature, whose options are then listed
+	UseGo func(featureName string) bool
+}
+
+// Execute lists the available commands. It is also used for commands we
+// don't know, in which case the listing goes to stderr and the command fails.
+func (c *Command) Execute() error {
+	if c.Args.CommandType == commandargs.Help {
+		c.displayCommands(c.ReadWriter.Out)
+		return nil
+	}
+
+	c.displayCommands(c.ReadWriter.ErrOut)
+
+	return fmt.Errorf("Unknown command: '%s'", c.Args.SshArgs[0])
+}
+
+func (c *Command) displayCommands(out io.Writer) {
+	fmt.Fprintln(out, "Available commands:")
+	fmt.Fprintln(out)
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, info := range AvailableCommands(c.Config) {
+		usage := info.Usage
+		if c.UseGo(string(info.Type)) {
+			usage = info.GoUsage()
+		}
+
+		if usage == "" {
+			usage = "(no command)"
+		}
+
+		fmt.Fprintf(writer, "  %s\t%s\n", usage, info.Description)
+	}
+	writer.Flush()
+}
+
+// AvailableCommands returns the registered commands this installation
+// supports and didn't disable
+func AvailableCommands(config *config.Config) []*commandargs.CommandInfo {
+	var available []*commandargs.CommandInfo
+
+	for _, info := range commandargs.Commands {
+		if !config.CommandEnabled(string(info.Type)) {
+			continue
+		}
+
+		// Help always runs in Go, see command.buildCommand
+		if info.RubyImplemented || info.Type == commandargs.Help || config.FeatureEnabled(string(info.Type)) {
+			available = append(available, info)
+		}
+	}
+
+	return available
+}
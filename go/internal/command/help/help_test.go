@@ -0,0 +1,129 @@
+package help
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+func TestExecute(t *testing.T) {
+	disabled := false
+
+	testCases := []struct {
+		desc           string
+		arguments      *commandargs.CommandArgs
+		features       []string
+		commands       map[string]config.CommandConfig
+		expectedOutput string
+		expectedStderr string
+		expectedError  string
+	}{
+		{
+			desc:      "It lists the commands",
+			arguments: &commandargs.CommandArgs{CommandType: commandargs.Help, SshArgs: []string{"help"}},
+			expectedOutput: "Available commands:\n\n" +
+				"  (no command)                                         Show which user you are authenticated as\n" +
+				"  2fa_recovery_codes                                   Generate new two-factor recovery codes\n" +
+				"  git-upload-pack <repository>                         Fetch from a repository (used by git fetch, pull and clone)\n" +
+				"  git-receive-pack <repository>                        Push to a repository (used by git push)\n" +
+				"  git-upload-archive <repository>                      Download an archive of a repository (used by git archive)\n" +
+				"  git-lfs-authenticate <repository> <download|upload>  Authenticate a Git LFS transfer\n" +
+				"  help                                                 List the available commands\n",
+		},
+		{
+			desc:      "It lists the commands enabled in Go",
+			arguments: &commandargs.CommandArgs{CommandType: commandargs.Help, SshArgs: []string{"help"}},
+			features:  []string{"personal_access_token"},
+			expectedOutput: "Available commands:\n\n" +
+				"  (no command)                                                   Show which user you are authenticated as\n" +
+				"  2fa_recovery_codes                                             Generate new two-factor recovery codes\n" +
+				"  personal_access_token <name> <scope1[,scope2,...]> [ttl_days]  Create a personal access token\n" +
+				"  git-upload-pack <repository>                                   Fetch from a repository (used by git fetch, pull and clone)\n" +
+				"  git-receive-pack <repository>                                  Push to a repository (used by git push)\n" +
+				"  git-upload-archive <repository>                                Download an archive of a repository (used by git archive)\n" +
+				"  git-lfs-authenticate <repository> <download|upload>            Authenticate a Git LFS transfer\n" +
+				"  help                                                           List the available commands\n",
+		},
+		{
+			desc:      "It lists the options of the Go implementations",
+			arguments: &commandargs.CommandArgs{CommandType: commandargs.Help, SshArgs: []string{"help"}},
+			features:  []string{"2fa_recovery_codes"},
+			expectedOutput: "Available commands:\n\n" +
+				"  (no command)                                         Show which user you are authenticated as\n" +
+				"  2fa_recovery_codes [--yes] [--format=text|json]      Generate new two-factor recovery codes\n" +
+				"  git-upload-pack <repository>                         Fetch from a repository (used by git fetch, pull and clone)\n" +
+				"  git-receive-pack <repository>                        Push to a repository (used by git push)\n" +
+				"  git-upload-archive <repository>                      Download an archive of a repository (used by git archive)\n" +
+				"  git-lfs-authenticate <repository> <download|upload>  Authenticate a Git LFS transfer\n" +
+				"  help                                                 List the available commands\n",
+		},
+		{
+			desc:      "It doesn't list disabled commands",
+			arguments: &commandargs.CommandArgs{CommandType: commandargs.Help, SshArgs: []string{"help"}},
+			commands: map[string]config.CommandConfig{
+				"2fa_recovery_codes": {Enabled: &disabled},
+				"git-upload-archive": {Enabled: &disabled},
+			},
+			expectedOutput: "Available commands:\n\n" +
+				"  (no command)                                         Show which user you are authenticated as\n" +
+				"  git-upload-pack <repository>                         Fetch from a repository (used by git fetch, pull and clone)\n" +
+				"  git-receive-pack <repository>                        Push to a repository (used by git push)\n" +
+				"  git-lfs-authenticate <repository> <download|upload>  Authenticate a Git LFS transfer\n" +
+				"  help                                                 List the available commands\n",
+		},
+		{
+			desc:      "It lists the commands on stderr for unknown commands",
+			arguments: &commandargs.CommandArgs{SshArgs: []string{"foo", "bar"}},
+			expectedStderr: "Available commands:\n\n" +
+				"  (no command)                                         Show which user you are authenticated as\n" +
+				"  2fa_recovery_codes                                   Generate new two-factor recovery codes\n" +
+				"  git-upload-pack <repository>                         Fetch from a repository (used by git fetch, pull and clone)\n" +
+				"  git-receive-pack <repository>                        Push to a repository (used by git push)\n" +
+				"  git-upload-archive <repository>                      Download an archive of a repository (used by git archive)\n" +
+				"  git-lfs-authenticate <repository> <download|upload>  Authenticate a Git LFS transfer\n" +
+				"  help                                                 List the available commands\n",
+			expectedError: "Unknown command: 'foo'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+
+			cmd := &Command{
+				Config: &config.Config{
+					Migration: config.MigrationConfig{Enabled: true, Features: tc.features},
+					Commands:  tc.commands,
+				},
+				Args:       tc.arguments,
+				ReadWriter: &readwriter.ReadWriter{Out: output, ErrOut: stderr},
+				UseGo: func(featureName string) bool {
+					for _, feature := range tc.features {
+						if feature == featureName {
+							return true
+						}
+					}
+
+					return false
+				},
+			}
+
+			err := cmd.Execute()
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+
+			assert.Equal(t, tc.expectedOutput, output.String())
+			assert.Equal(t, tc.expectedStderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package keyssync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/authorizedkeys"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/keyline"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+const (
+	usage = "Usage: gitlab-shell-authorized-keys-sync export [--force] | diff [--fix [--force]] | list"
+
+	// header is what `gitlab-keys clear` writes
+	header = "# Managed by gitlab-shell"
+
+	// maxRemovedShare is the share of the keys of the file a write may remove
+	// without --force
+	maxRemovedShare = 0.5
+)
+
+// Command keeps the auth_file in sync with the keys GitLab knows:
+//
+//	gitlab-shell-authorized-keys-sync export [--force]
+//	gitlab-shell-authorized-keys-sync diff [--fix [--force]]
+//	gitlab-shell-authorized-keys-sync list
+//
+// export replaces the file with every valid key from GitLab. diff reports the
+// keys missing from the file, the extra ones GitLab doesn't know, and the
+// stale ones whose public key changed. With --fix, the file is replaced when
+// it is out of sync. Both refuse to write no keys at all, or to remove more
+// than half of the keys of the file, unless --force is given. list prints the
+// keys of the file with their SHA256 fingerprints.
+type Command struct {
+	Config     *config.Config
+	Args       []string
+	ReadWriter *readwriter.ReadWriter
+}
+
+type drift struct {
+	missing []string
+	extra   []string
+	stale   []string
+}
+
+func (c *Command) Execute() error {
+	switch {
+	case len(c.Args) == 1 && c.Args[0] == "export":
+		return c.export(false)
+	case len(c.Args) == 2 && c.Args[0] == "export" && c.Args[1] == "--force":
+		return c.export(true)
+	case len(c.Args) == 1 && c.Args[0] == "diff":
+		return c.diff(false, false)
+	case len(c.Args) == 2 && c.Args[0] == "diff" && c.Args[1] == "--fix":
+		return c.diff(true, false)
+	case len(c.Args) == 3 && c.Args[0] == "diff" && c.Args[1] == "--fix" && c.Args[2] == "--force":
+		return c.diff(true, true)
+	case len(c.Args) == 1 && c.Args[0] == "list":
+		return c.list()
+	default:
+		return errors.New(usage)
+	}
+}
+
+func (c *Command) export(force bool) error {
+	keys, err := c.keyLines()
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		lines, err := keyline.ReadFile(c.Config.AuthFile, c.Config.RootDir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := c.checkShrink(keys, lines); err != nil {
+			return err
+		}
+	}
+
+	if err := c.writeAuthFile(keys); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.ReadWriter.Out, "Wrote %d keys to %s\n", len(keys), c.Config.AuthFile)
+
+	return nil
+}
+
+func (c *Command) diff(fix, force bool) error {
+	keys, err := c.keyLines()
+	if err != nil {
+		return err
+	}
+
+	lines, err := keyline.ReadFile(c.Config.AuthFile, c.Config.RootDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	d := compare(keys, lines)
+	for _, entry := range []struct {
+		kind string
+		ids  []string
+	}{{"missing", d.missing}, {"extra", d.extra}, {"stale", d.stale}} {
+		for _, id := range entry.ids {
+			fmt.Fprintf(c.ReadWriter.Out, "%s key-%s\n", entry.kind, id)
+		}
+	}
+
+	if d.empty() {
+		fmt.Fprintf(c.ReadWriter.Out, "%s is in sync with GitLab (%d keys)\n", c.Config.AuthFile, len(keys))
+		return nil
+	}
+
+	summary := fmt.Sprintf("%s has %d missing, %d extra and %d stale keys", c.Config.AuthFile, len(d.missing), len(d.extra), len(d.stale))
+	if !fix {
+		return errors.New(summary)
+	}
+
+	fmt.Fprintln(c.ReadWriter.Out, summary)
+
+	if !force {
+		if err := c.checkShrink(keys, lines); err != nil {
+			return err
+		}
+	}
+
+	if err := c.writeAuthFile(keys); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.ReadWriter.Out, "Wrote %d keys to %s\n", len(keys), c.Config.AuthFile)
+
+	return nil
+}
+
+func (c *Command) list() error {
+	lines, err := keyline.ReadFile(c.Config.AuthFile, c.Config.RootDir)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if line.Prefix != keyline.PublicKeyPrefix {
+			continue
+		}
+
+		key, err := line.PublicKey()
+		if err != nil {
+			fmt.Fprintf(c.ReadWriter.Out, "key-%s invalid: %v\n", line.Id, err)
+			continue
+		}
+
+		fmt.Fprintf(c.ReadWriter.Out, "key-%s %s %s\n", line.Id, key.Fingerprint(), strings.TrimSpace(key.Type()+" "+key.Comment))
+	}
+
+	return nil
+}
+
+// keyLines returns the lines for the keys GitLab knows. Invalid keys are
+// skipped, and reported.
+func (c *Command) keyLines() ([]*keyline.KeyLine, error) {
+	client, err := authorizedkeys.NewClient(c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := client.ListKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []*keyline.KeyLine
+	for _, key := range keys {
+		id := strconv.FormatInt(key.Id, 10)
+
+		line, err := keyline.NewPublicKeyLine(id, key.Key, c.Config.RootDir)
+		if err != nil {
+			logger.Warn("Skipped an invalid key", map[string]interface{}{"key_id": id, "error": err.Error()})
+			fmt.Fprintf(c.ReadWriter.Out, "skipped key-%s: %v\n", id, err)
+			continue
+		}
+
+		if err := line.SetOptions(keyline.NewOptions(c.Config.KeyOptions, key.ExpiresAt)); err != nil {
+			return nil, fmt.Errorf("Invalid authorized_keys_options: %v", err)
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// checkShrink refuses to replace the lines of the file with the keys when
+// there are none, or when more than maxRemovedShare of the keys would be
+// removed. Both are more likely to come from a broken API response than from
+// users deleting their keys.
+func (c *Command) checkShrink(keys []*keyline.KeyLine, lines []*keyline.KeyLine) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("Refusing to write no keys to %s, use --force to write it anyway", c.Config.AuthFile)
+	}
+
+	fileKeys := 0
+	for _, line := range lines {
+		if line.Prefix == keyline.PublicKeyPrefix {
+			fileKeys++
+		}
+	}
+
+	if removed := fileKeys - len(keys); float64(removed) > float64(fileKeys)*maxRemovedShare {
+		return fmt.Errorf("Refusing to remove %d of the %d keys of %s, use --force to write it anyway", removed, fileKeys, c.Config.AuthFile)
+	}
+
+	return nil
+}
+
+// compare matches the keys from GitLab with the lines of the file by key id
+func compare(keys []*keyline.KeyLine, lines []*keyline.KeyLine) *drift {
+	fileBlobs := map[string]string{}
+	for _, line := range lines {
+		if line.Prefix == keyline.PublicKeyPrefix {
+			fileBlobs[line.Id] = line.Blob()
+		}
+	}
+
+	d := &drift{}
+	apiIds := map[string]bool{}
+	for _, key := range keys {
+		apiIds[key.Id] = true
+
+		blob, ok := fileBlobs[key.Id]
+		if !ok {
+			d.missing = append(d.missing, key.Id)
+		} else if blob != key.Blob() {
+			d.stale = append(d.stale, key.Id)
+		}
+	}
+
+	for id := range fileBlobs {
+		if !apiIds[id] {
+			d.extra = append(d.extra, id)
+		}
+	}
+
+	for _, ids := range [][]string{d.missing, d.extra, d.stale} {
+		sortIds(ids)
+	}
+
+	return d
+}
+
+func (d *drift) empty() bool {
+	return len(d.missing) == 0 && len(d.extra) == 0 && len(d.stale) == 0
+}
+
+func sortIds(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		return a < b
+	})
+}
+
+// writeAuthFile replaces the auth_file with the keys. It holds the lock
+// gitlab-keys uses, and readers like sshd see either the old or the new file.
+func (c *Command) writeAuthFile(keys []*keyline.KeyLine) error {
+	var buffer bytes.Buffer
+	fmt.Fprintln(&buffer, header)
+
+	for _, key := range keys {
+		fmt.Fprintln(&buffer, key.ToString())
+	}
+
+	return withLock(c.Config.AuthFile+".lock", func() error {
+		return writeAtomically(c.Config.AuthFile, buffer.Bytes())
+	})
+}
+
+func withLock(path string, fn func() error) error {
+	lockFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer lockFile.Close()
+
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
+
+func writeAtomically(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,235 @@
+package keyssync
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/testserver"
+)
+
+const (
+	options = `,no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty `
+
+	rsaKey     = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDhI/FW0RAfp6JU9U4Eb7lDxWrvyrpdf/ehnKCJNd9ee6B5UO9JCAyuSkU7ipCgX+HLDE6oYgCziSux4XTSWyK1CISbd19oLuGQl9FAMHDQ3s2iVrT5xRoOtCXzl7SsW49G2miuNDIqsnlBptxYPFQ5J0aeHbU33/cvgsWFadbh+Q=="
+	ed25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB1WPaqcY4HOEnrYcVnLTPUT8E9tfc2ZkDWpmXdvTk8P"
+	ecdsaKey   = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBPvjXnvFm4N44qwdVa+IoQGcqd/8sJFheUPQ3ThiUa/7C45UJS7AVHusIQSyJ52NpLPom4SWyvYRbWzQprxa/Xc="
+	skKey      = "sk-ssh-ed25519@openssh.com AAAAGnNrLXNzaC1lZDI1NTE5QG9wZW5zc2guY29tAAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4fAAAABHNzaDo="
+
+	expectedAuthFile = "# Managed by gitlab-shell\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-1"` + options + rsaKey + "\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-2"` + options + ed25519Key + " jane@example.com\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-10"` + options + skKey + "\n"
+
+	skippedOutput = "skipped key-11: Invalid public key: ssh: short read\n"
+)
+
+var (
+	requests = []testserver.TestRequestHandler{
+		{
+			Path: "/api/v4/internal/authorized_keys/list",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("page") == "1" {
+					w.Header().Set("X-Next-Page", "2")
+					json.NewEncoder(w).Encode([]map[string]interface{}{
+						{"id": 1, "key": rsaKey},
+						{"id": 2, "key": ed25519Key + "  jane@example.com"},
+					})
+				} else {
+					json.NewEncoder(w).Encode([]map[string]interface{}{
+						{"id": 10, "key": skKey},
+						{"id": 11, "key": "ssh-rsa AAAA"},
+					})
+				}
+			},
+		},
+	}
+)
+
+func setup(t *testing.T) (*config.Config, func()) {
+	return setupWithRequests(t, requests)
+}
+
+func setupWithRequests(t *testing.T, requests []testserver.TestRequestHandler) (*config.Config, func()) {
+	cleanupServer, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "keyssync")
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		RootDir:   "/opt/gitlab-shell",
+		GitlabUrl: url,
+		AuthFile:  filepath.Join(dir, "authorized_keys"),
+	}
+
+	return cfg, func() {
+		cleanupServer()
+		os.RemoveAll(dir)
+	}
+}
+
+func execute(cfg *config.Config, args ...string) (string, error) {
+	buffer := &bytes.Buffer{}
+	cmd := &Command{Config: cfg, Args: args, ReadWriter: &readwriter.ReadWriter{Out: buffer}}
+
+	err := cmd.Execute()
+
+	return buffer.String(), err
+}
+
+func TestExport(t *testing.T) {
+	cfg, cleanup := setup(t)
+	defer cleanup()
+
+	require.NoError(t, ioutil.WriteFile(cfg.AuthFile, []byte("outdated\n"), 0644))
+
+	output, err := execute(cfg, "export")
+	require.NoError(t, err)
+	assert.Equal(t, skippedOutput+"Wrote 3 keys to "+cfg.AuthFile+"\n", output)
+
+	content, err := ioutil.ReadFile(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, expectedAuthFile, string(content))
+
+	info, err := os.Stat(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestExportWithoutKeys(t *testing.T) {
+	cfg, cleanup := setupWithRequests(t, []testserver.TestRequestHandler{
+		{
+			Path: "/api/v4/internal/authorized_keys/list",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("[]"))
+			},
+		},
+	})
+	defer cleanup()
+
+	require.NoError(t, ioutil.WriteFile(cfg.AuthFile, []byte(expectedAuthFile), 0600))
+
+	_, err := execute(cfg, "export")
+	assert.EqualError(t, err, "Refusing to write no keys to "+cfg.AuthFile+", use --force to write it anyway")
+
+	content, err := ioutil.ReadFile(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, expectedAuthFile, string(content), "the file is kept")
+
+	output, err := execute(cfg, "export", "--force")
+	require.NoError(t, err)
+	assert.Equal(t, "Wrote 0 keys to "+cfg.AuthFile+"\n", output)
+
+	content, err = ioutil.ReadFile(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# Managed by gitlab-shell\n", string(content))
+}
+
+func TestExportRemovingMostKeys(t *testing.T) {
+	cfg, cleanup := setup(t)
+	defer cleanup()
+
+	// GitLab returns 3 of these 7 keys
+	crowded := expectedAuthFile
+	for _, id := range []string{"20", "21", "22", "23"} {
+		crowded += `command="/opt/gitlab-shell/bin/gitlab-shell key-` + id + `"` + options + ecdsaKey + "\n"
+	}
+	require.NoError(t, ioutil.WriteFile(cfg.AuthFile, []byte(crowded), 0600))
+
+	_, err := execute(cfg, "export")
+	assert.EqualError(t, err, "Refusing to remove 4 of the 7 keys of "+cfg.AuthFile+", use --force to write it anyway")
+
+	output, err := execute(cfg, "diff", "--fix")
+	assert.EqualError(t, err, "Refusing to remove 4 of the 7 keys of "+cfg.AuthFile+", use --force to write it anyway")
+	assert.Equal(t, skippedOutput+"extra key-20\nextra key-21\nextra key-22\nextra key-23\n"+
+		cfg.AuthFile+" has 0 missing, 4 extra and 0 stale keys\n", output)
+
+	content, err := ioutil.ReadFile(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, crowded, string(content), "the file is kept")
+
+	_, err = execute(cfg, "diff", "--fix", "--force")
+	require.NoError(t, err)
+
+	content, err = ioutil.ReadFile(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, expectedAuthFile, string(content))
+}
+
+func TestDiff(t *testing.T) {
+	cfg, cleanup := setup(t)
+	defer cleanup()
+
+	outdated := "# Managed by gitlab-shell\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-2"` + options + "ssh-ed25519 OLD jane@example.com\n" +
+		"#########################################\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-7"` + options + ecdsaKey + "\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-10"` + options + skKey + "\n"
+	require.NoError(t, ioutil.WriteFile(cfg.AuthFile, []byte(outdated), 0600))
+
+	output, err := execute(cfg, "diff")
+	assert.EqualError(t, err, cfg.AuthFile+" has 1 missing, 1 extra and 1 stale keys")
+	assert.Equal(t, skippedOutput+"missing key-1\nextra key-7\nstale key-2\n", output)
+
+	content, err := ioutil.ReadFile(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, outdated, string(content), "the file is only reported on")
+
+	output, err = execute(cfg, "diff", "--fix")
+	require.NoError(t, err)
+	assert.Equal(t, skippedOutput+"missing key-1\nextra key-7\nstale key-2\n"+
+		cfg.AuthFile+" has 1 missing, 1 extra and 1 stale keys\n"+
+		"Wrote 3 keys to "+cfg.AuthFile+"\n", output)
+
+	content, err = ioutil.ReadFile(cfg.AuthFile)
+	require.NoError(t, err)
+	assert.Equal(t, expectedAuthFile, string(content))
+
+	output, err = execute(cfg, "diff")
+	require.NoError(t, err)
+	assert.Equal(t, skippedOutput+cfg.AuthFile+" is in sync with GitLab (3 keys)\n", output)
+}
+
+func TestDiffWithoutAuthFile(t *testing.T) {
+	cfg, cleanup := setup(t)
+	defer cleanup()
+
+	output, err := execute(cfg, "diff")
+	assert.EqualError(t, err, cfg.AuthFile+" has 3 missing, 0 extra and 0 stale keys")
+	assert.Equal(t, skippedOutput+"missing key-1\nmissing key-2\nmissing key-10\n", output)
+}
+
+func TestList(t *testing.T) {
+	cfg, cleanup := setup(t)
+	defer cleanup()
+
+	content := expectedAuthFile +
+		`command="/opt/gitlab-shell/bin/gitlab-shell key-7"` + options + "ssh-rsa AAAA\n" +
+		`command="/opt/gitlab-shell/bin/gitlab-shell username-jane"` + options + "principal\n"
+	require.NoError(t, ioutil.WriteFile(cfg.AuthFile, []byte(content), 0600))
+
+	output, err := execute(cfg, "list")
+	require.NoError(t, err)
+	assert.Equal(t, "key-1 SHA256:2uGLfmJGusc4Hh6mPZQytS5+oUplrmXQliqU46wUdac ssh-rsa\n"+
+		"key-2 SHA256:nIaNY9q8rz8HmE+S9GW2KzVgZuMUj5lf/cxHZvFswbY ssh-ed25519 jane@example.com\n"+
+		"key-10 SHA256:/p0CbeE3dk2SyW1OXXsThGc12ezDVD8eGw2/vtztDfk sk-ssh-ed25519@openssh.com\n"+
+		"key-7 invalid: ssh: short read\n", output)
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"import"}, {"diff", "--force"}, {"diff", "--force", "--fix"}, {"export", "extra"}, {"list", "--all"}} {
+		_, err := execute(&config.Config{}, args...)
+
+		assert.EqualError(t, err, usage)
+	}
+}
@@ -0,0 +1,125 @@
+package personalaccesstoken
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/personalaccesstoken"
+)
+
+const (
+	usageText         = "Usage: personal_access_token <name> <scope1[,scope2,...]> [ttl_days]"
+	expiresDateFormat = "2006-01-02"
+)
+
+var (
+	// validScopes are the scopes GitLab accepts for personal access tokens
+	validScopes = []string{
+		"api",
+		"read_user",
+		"read_api",
+		"read_repository",
+		"write_repository",
+		"read_registry",
+		"write_registry",
+		"sudo",
+	}
+
+	// nowFunc is overridden in tests
+	nowFunc = time.Now
+)
+
+type Command struct {
+	Config     *config.Config
+	Args       *commandargs.CommandArgs
+	ReadWriter *readwriter.ReadWriter
+}
+
+type tokenArgs struct {
+	name      string
+	scopes    []string
+	expiresAt string
+}
+
+func (c *Command) Execute() error {
+	tokenArgs, err := c.parseTokenArgs()
+	if err != nil {
+		return err
+	}
+
+	response, err := c.getPersonalAccessToken(tokenArgs)
+	if err != nil {
+		// The token may have been created already unless the API asked for a
+		// custom action
+		if unsupported := fallback.CustomAction(err); unsupported != nil {
+			return unsupported
+		}
+
+		return err
+	}
+
+	expiresAt := response.ExpiresAt
+	if expiresAt == "" {
+		expiresAt = "never"
+	}
+
+	fmt.Fprintf(c.ReadWriter.Out, "Token:   %s\n", response.Token)
+	fmt.Fprintf(c.ReadWriter.Out, "Scopes:  %s\n", strings.Join(response.Scopes, ","))
+	fmt.Fprintf(c.ReadWriter.Out, "Expires: %s\n", expiresAt)
+
+	return nil
+}
+
+func (c *Command) parseTokenArgs() (*tokenArgs, error) {
+	// The first argument is the command itself
+	args := c.Args.SshArgs
+	if len(args) < 3 || len(args) > 4 {
+		return nil, errors.New(usageText)
+	}
+
+	scopes := strings.Split(args[2], ",")
+	for _, scope := range scopes {
+		if !isValidScope(scope) {
+			return nil, fmt.Errorf("Invalid scope: '%s'. Valid scopes are: %s", scope, strings.Join(validScopes, ", "))
+		}
+	}
+
+	parsed := &tokenArgs{name: args[1], scopes: scopes}
+
+	if len(args) == 4 {
+		ttlDays, err := strconv.ParseUint(args[3], 10, 16)
+		if err != nil || ttlDays == 0 {
+			return nil, fmt.Errorf("Invalid value for ttl_days: '%s'. It must be a positive number of days.", args[3])
+		}
+
+		parsed.expiresAt = nowFunc().AddDate(0, 0, int(ttlDays)).Format(expiresDateFormat)
+	}
+
+	return parsed, nil
+}
+
+func isValidScope(scope string) bool {
+	for _, validScope := range validScopes {
+		if scope == validScope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Command) getPersonalAccessToken(tokenArgs *tokenArgs) (*personalaccesstoken.Response, error) {
+	client, err := personalaccesstoken.NewClient(c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetPersonalAccessToken(c.Args, tokenArgs.name, tokenArgs.scopes, tokenArgs.expiresAt)
+}
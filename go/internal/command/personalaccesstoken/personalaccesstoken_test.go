@@ -0,0 +1,179 @@
+package personalaccesstoken
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/personalaccesstoken"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/testserver"
+)
+
+var (
+	requests []testserver.TestRequestHandler
+)
+
+func setup(t *testing.T) {
+	requests = []testserver.TestRequestHandler{
+		{
+			Path: "/api/v4/internal/personal_access_token",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				defer r.Body.Close()
+
+				require.NoError(t, err)
+
+				var requestBody *personalaccesstoken.RequestBody
+				json.Unmarshal(b, &requestBody)
+
+				switch requestBody.KeyId {
+				case "1":
+					body := map[string]interface{}{
+						"success":    true,
+						"token":      "YXuxvUgCEmeePY3G1YAa",
+						"scopes":     requestBody.Scopes,
+						"expires_at": requestBody.ExpiresAt,
+					}
+					json.NewEncoder(w).Encode(body)
+				case "forbidden":
+					body := map[string]interface{}{
+						"success": false,
+						"message": "Forbidden!",
+					}
+					json.NewEncoder(w).Encode(body)
+				case "broken":
+					w.WriteHeader(http.StatusInternalServerError)
+				case "custom_action":
+					w.WriteHeader(http.StatusMultipleChoices)
+					json.NewEncoder(w).Encode(map[string]interface{}{})
+				}
+			},
+		},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	setup(t)
+
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	nowFunc = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	defer func() { nowFunc = time.Now }()
+
+	testCases := []struct {
+		desc           string
+		arguments      *commandargs.CommandArgs
+		expectedOutput string
+		expectedError  string
+	}{
+		{
+			desc: "Without any arguments",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"},
+				SshArgs: []string{"personal_access_token"},
+			},
+			expectedError: usageText,
+		},
+		{
+			desc: "With too many arguments",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"},
+				SshArgs: []string{"personal_access_token", "newtoken", "api", "30", "extra"},
+			},
+			expectedError: usageText,
+		},
+		{
+			desc: "With an invalid scope",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"},
+				SshArgs: []string{"personal_access_token", "newtoken", "api,admin"},
+			},
+			expectedError: "Invalid scope: 'admin'. Valid scopes are: api, read_user, read_api, read_repository, write_repository, read_registry, write_registry, sudo",
+		},
+		{
+			desc: "With an invalid ttl_days",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"},
+				SshArgs: []string{"personal_access_token", "newtoken", "api", "-30"},
+			},
+			expectedError: "Invalid value for ttl_days: '-30'. It must be a positive number of days.",
+		},
+		{
+			desc: "Without a ttl_days",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"},
+				SshArgs: []string{"personal_access_token", "newtoken", "read_api,read_repository"},
+			},
+			expectedOutput: "Token:   YXuxvUgCEmeePY3G1YAa\n" +
+				"Scopes:  read_api,read_repository\n" +
+				"Expires: never\n",
+		},
+		{
+			desc: "With a ttl_days",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"},
+				SshArgs: []string{"personal_access_token", "newtoken", "api", "30"},
+			},
+			expectedOutput: "Token:   YXuxvUgCEmeePY3G1YAa\n" +
+				"Scopes:  api\n" +
+				"Expires: 2026-11-18\n",
+		},
+		{
+			desc: "With API returns an error",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "forbidden"},
+				SshArgs: []string{"personal_access_token", "newtoken", "api"},
+			},
+			expectedError: "Forbidden!",
+		},
+		{
+			desc: "With API fails",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "broken"},
+				SshArgs: []string{"personal_access_token", "newtoken", "api"},
+			},
+			expectedError: "Internal API error (500)",
+		},
+		{
+			desc: "With API asking for a custom action",
+			arguments: &commandargs.CommandArgs{
+				Who:     &commandargs.Who{Kind: commandargs.WhoKey, Value: "custom_action"},
+				SshArgs: []string{"personal_access_token", "newtoken", "api"},
+			},
+			expectedError: "Not supported by gitlab-shell: custom action",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			output := &bytes.Buffer{}
+
+			cmd := &Command{
+				Config:     &config.Config{GitlabUrl: url},
+				Args:       tc.arguments,
+				ReadWriter: &readwriter.ReadWriter{Out: output},
+			}
+
+			err := cmd.Execute()
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+
+			assert.Equal(t, tc.expectedOutput, output.String())
+		})
+	}
+}
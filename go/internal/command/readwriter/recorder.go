@@ -0,0 +1,17 @@
+package readwriter
+
+import "io"
+
+// Recorder is a writer that records whether anything was written through it
+type Recorder struct {
+	Writer  io.Writer
+	Written bool
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		r.Written = true
+	}
+
+	return r.Writer.Write(p)
+}
@@ -0,0 +1,23 @@
+package readwriter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	out := &bytes.Buffer{}
+	recorder := &Recorder{Writer: out}
+
+	_, err := recorder.Write(nil)
+	require.NoError(t, err)
+	require.False(t, recorder.Written)
+
+	n, err := recorder.Write([]byte("Hello"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.True(t, recorder.Written)
+	require.Equal(t, "Hello", out.String())
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+const (
+	goImplementation   = "go"
+	rubyImplementation = "ruby"
+)
+
+var (
+	nowFunc = time.Now
+)
+
+// useGo tells whether the session runs the Go implementation of a migrated
+// command, following the rollout rules of its feature, and why
+func useGo(featureName string, args *commandargs.CommandArgs, config *config.Config) (bool, string) {
+	if !config.FeatureEnabled(featureName) {
+		return false, "feature disabled"
+	}
+
+	rollout := config.FeatureRollout(featureName)
+	if rollout == nil {
+		return true, "feature enabled"
+	}
+
+	if !rollout.InWindow(nowFunc()) {
+		return false, "outside of the rollout windows"
+	}
+
+	if keyId := args.Who.ValueFor(commandargs.WhoKey); keyId != "" && contains(rollout.KeyIds, keyId) {
+		return true, "key in the rollout"
+	}
+
+	if username := args.Who.ValueFor(commandargs.WhoUsername); username != "" && contains(rollout.Usernames, username) {
+		return true, "username in the rollout"
+	}
+
+	if sessionBucket(featureName) < rollout.Percentage {
+		return true, fmt.Sprintf("session in the %d%% rollout", rollout.Percentage)
+	}
+
+	return false, fmt.Sprintf("session outside of the %d%% rollout", rollout.Percentage)
+}
+
+// sessionBucket places the SSH session in one of 100 buckets. The client
+// port in SSH_CONNECTION differs between sessions.
+func sessionBucket(featureName string) uint {
+	hash := fnv.New32a()
+	hash.Write([]byte(featureName + " " + os.Getenv("SSH_CONNECTION")))
+
+	return uint(hash.Sum32() % 100)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func logImplementation(args *commandargs.CommandArgs, implementation, reason string) {
+	logger.Info("Chose the command implementation", map[string]interface{}{
+		"command":        args.SshCommand,
+		"user":           args.LogUsername(),
+		"implementation": implementation,
+		"reason":         reason,
+	})
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/testhelper"
+)
+
+func TestUseGo(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{"SSH_CONNECTION": "192.0.2.1 51234 10.0.0.1 22"})
+	defer restoreEnv()
+
+	oldNowFunc := nowFunc
+	nowFunc = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	defer func() { nowFunc = oldNowFunc }()
+
+	key := &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}}
+	username := &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoUsername, Value: "jane-doe"}}
+	other := &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "2"}}
+
+	migration := func(rollout *config.RolloutConfig) *config.Config {
+		cfg := &config.Config{Migration: config.MigrationConfig{Enabled: true, Features: []string{"discover"}}}
+		if rollout != nil {
+			cfg.Migration.Rollout = map[string]config.RolloutConfig{"discover": *rollout}
+		}
+
+		return cfg
+	}
+
+	listed := &config.RolloutConfig{KeyIds: []string{"1"}, Usernames: []string{"jane-doe"}}
+
+	testCases := []struct {
+		desc           string
+		config         *config.Config
+		args           *commandargs.CommandArgs
+		expectedGo     bool
+		expectedReason string
+	}{
+		{
+			desc:           "When the feature is disabled",
+			config:         &config.Config{},
+			args:           key,
+			expectedReason: "feature disabled",
+		},
+		{
+			desc:           "When the feature has no rollout",
+			config:         migration(nil),
+			args:           other,
+			expectedGo:     true,
+			expectedReason: "feature enabled",
+		},
+		{
+			desc:           "For a listed key",
+			config:         migration(listed),
+			args:           key,
+			expectedGo:     true,
+			expectedReason: "key in the rollout",
+		},
+		{
+			desc:           "For a listed username",
+			config:         migration(listed),
+			args:           username,
+			expectedGo:     true,
+			expectedReason: "username in the rollout",
+		},
+		{
+			desc:           "For sessions in the percentage",
+			config:         migration(&config.RolloutConfig{Percentage: 100}),
+			args:           other,
+			expectedGo:     true,
+			expectedReason: "session in the 100% rollout",
+		},
+		{
+			desc:           "For sessions outside of the percentage",
+			config:         migration(listed),
+			args:           other,
+			expectedReason: "session outside of the 0% rollout",
+		},
+		{
+			desc: "Outside of the windows",
+			config: migration(&config.RolloutConfig{
+				Percentage: 100,
+				KeyIds:     []string{"1"},
+				Windows:    []config.RolloutWindowConfig{{StartsAt: "2026-10-20T00:00:00Z"}},
+			}),
+			args:           key,
+			expectedReason: "outside of the rollout windows",
+		},
+		{
+			desc: "In a window",
+			config: migration(&config.RolloutConfig{
+				KeyIds:  []string{"1"},
+				Windows: []config.RolloutWindowConfig{{EndsAt: "2026-10-20T00:00:00Z"}},
+			}),
+			args:           key,
+			expectedGo:     true,
+			expectedReason: "key in the rollout",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			useGo, reason := useGo("discover", tc.args, tc.config)
+
+			assert.Equal(t, tc.expectedGo, useGo)
+			assert.Equal(t, tc.expectedReason, reason)
+		})
+	}
+}
+
+func TestSessionBucket(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{"SSH_CONNECTION": "192.0.2.1 51234 10.0.0.1 22"})
+	defer restoreEnv()
+
+	bucket := sessionBucket("discover")
+	assert.True(t, bucket < 100)
+	assert.Equal(t, bucket, sessionBucket("discover"), "a session stays in its bucket")
+
+	buckets := map[uint]bool{}
+	for port := 50000; port < 50100; port++ {
+		restoreEnv := testhelper.TempEnv(map[string]string{"SSH_CONNECTION": fmt.Sprintf("192.0.2.1 %d 10.0.0.1 22", port)})
+		buckets[sessionBucket("discover")] = true
+		restoreEnv()
+	}
+	assert.True(t, len(buckets) > 10, "sessions are spread over buckets")
+}
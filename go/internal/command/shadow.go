@@ -0,0 +1,210 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/discover"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+var (
+	// shadowable holds the commands whose Ruby implementation can run in
+	// the shadow of the Go one. They must exist in Ruby, have no side
+	// effects, and never read input, as the Ruby one runs without any.
+	shadowable = map[commandargs.CommandType]bool{
+		commandargs.Discover: true,
+	}
+
+	// goOnlyOutput removes the parts of the Go output the Ruby implementation
+	// doesn't have, so they aren't reported as differences
+	goOnlyOutput = map[commandargs.CommandType]func([]byte) []byte{
+		commandargs.Discover: discover.WithoutAnnouncements,
+	}
+
+	// shadowTimeout is how long the Ruby implementation may run. It's
+	// killed after that, so the Go one is never held up for longer.
+	shadowTimeout = 5 * time.Second
+)
+
+// shadowCommand runs the Go implementation of a command for real, and the
+// Ruby one at the same time with its output captured. Differences in their
+// output and exit codes are logged.
+type shadowCommand struct {
+	command Command
+	ruby    *fallback.Command
+	args    *commandargs.CommandArgs
+	stdout  *bytes.Buffer
+	stderr  *bytes.Buffer
+}
+
+type shadowResult struct {
+	result *fallback.Result
+	err    error
+}
+
+func newShadowCommand(build builder, arguments []string, args *commandargs.CommandArgs, config *config.Config, readWriter *readwriter.ReadWriter) *shadowCommand {
+	cmd := &shadowCommand{
+		ruby:   &fallback.Command{RootDir: config.RootDir, Args: arguments},
+		args:   args,
+		stdout: &bytes.Buffer{},
+		stderr: &bytes.Buffer{},
+	}
+
+	teeReadWriter := &readwriter.ReadWriter{
+		Out:    io.MultiWriter(readWriter.Out, cmd.stdout),
+		In:     readWriter.In,
+		ErrOut: io.MultiWriter(readWriter.ErrOut, cmd.stderr),
+	}
+	cmd.command = build(config, args, teeReadWriter)
+
+	return cmd
+}
+
+func (s *shadowCommand) Execute() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+	defer cancel()
+
+	shadow := make(chan shadowResult, 1)
+	go func() {
+		result, err := s.ruby.Capture(ctx)
+		shadow <- shadowResult{result: result, err: err}
+	}()
+
+	err := s.command.Execute()
+
+	goResult := &fallback.Result{Stdout: s.stdout.Bytes(), Stderr: s.stderr.Bytes()}
+	if err != nil {
+		// What main shows for errors
+		goResult.Stderr = append(goResult.Stderr, err.Error()+"\n"...)
+		goResult.ExitCode = 1
+	}
+
+	// main runs the Ruby implementation for real then, so there's nothing
+	// to compare
+	if _, ok := err.(*fallback.UnsupportedError); ok {
+		return err
+	}
+
+	var rubyResult shadowResult
+	select {
+	case rubyResult = <-shadow:
+	case <-ctx.Done():
+		// Ruby may not be done writing its output even after it was killed
+		rubyResult.err = ctx.Err()
+	}
+
+	if rubyResult.err == context.DeadlineExceeded {
+		logger.Warn("The shadow Ruby command timed out", map[string]interface{}{"command": s.args.SshCommand, "user": s.args.LogUsername()})
+		return err
+	}
+
+	if rubyResult.err != nil {
+		logger.Error("Failed to run the shadow Ruby command", rubyResult.err)
+		return err
+	}
+
+	if strip, ok := goOnlyOutput[s.args.CommandType]; ok {
+		goResult.Stdout = strip(goResult.Stdout)
+	}
+
+	if differences := compareResults(goResult, rubyResult.result); differences != nil {
+		differences["command"] = s.args.SshCommand
+		differences["user"] = s.args.LogUsername()
+
+		logger.Warn("The Go and Ruby implementations differ", differences)
+	}
+
+	return err
+}
+
+// compareResults returns the differences between the results, or nil if
+// there are none
+func compareResults(goResult, rubyResult *fallback.Result) map[string]interface{} {
+	differences := map[string]interface{}{}
+
+	if diff := diffLines(string(goResult.Stdout), string(rubyResult.Stdout)); diff != "" {
+		differences["stdout_diff"] = diff
+	}
+
+	if diff := diffLines(string(goResult.Stderr), string(rubyResult.Stderr)); diff != "" {
+		differences["stderr_diff"] = diff
+	}
+
+	if goResult.ExitCode != rubyResult.ExitCode {
+		differences["go_exit_code"] = goResult.ExitCode
+		differences["ruby_exit_code"] = rubyResult.ExitCode
+	}
+
+	if len(differences) == 0 {
+		return nil
+	}
+
+	return differences
+}
+
+// diffLines returns the lines only in the Go output prefixed with "-", and
+// those only in the Ruby output prefixed with "+", or an empty string if the
+// outputs are the same
+func diffLines(goOutput, rubyOutput string) string {
+	if goOutput == rubyOutput {
+		return ""
+	}
+
+	a, b := splitLines(goOutput), splitLines(rubyOutput)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("-" + strings.TrimSuffix(a[i], "\n") + "\n")
+			i++
+		default:
+			diff.WriteString("+" + strings.TrimSuffix(b[j], "\n") + "\n")
+			j++
+		}
+	}
+
+	return diff.String()
+}
+
+// splitLines splits the output after each newline, so a missing final
+// newline makes the last line differ
+func splitLines(output string) []string {
+	lines := strings.SplitAfter(output, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,140 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/fallback"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+type fakeCommand struct {
+	ReadWriter *readwriter.ReadWriter
+	Err        error
+}
+
+func (f *fakeCommand) Execute() error {
+	fmt.Fprint(f.ReadWriter.Out, "Welcome to GitLab, @jane-doe!\n")
+
+	return f.Err
+}
+
+func TestShadowExecute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shadow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "bin"), 0755))
+	script := "#!/bin/sh\necho 'Welcome to GitLab, @jane-doe!'\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bin", fallback.RubyProgram), []byte(script), 0755))
+
+	for _, expectedErr := range []error{nil, errors.New("Failed"), &fallback.UnsupportedError{Reason: "custom action"}} {
+		buffer := &bytes.Buffer{}
+		build := func(config *config.Config, args *commandargs.CommandArgs, readWriter *readwriter.ReadWriter) Command {
+			return &fakeCommand{ReadWriter: readWriter, Err: expectedErr}
+		}
+
+		cmd := newShadowCommand(
+			build,
+			[]string{"gitlab-shell", "key-1"},
+			&commandargs.CommandArgs{CommandType: commandargs.Discover},
+			&config.Config{RootDir: dir},
+			&readwriter.ReadWriter{Out: buffer, ErrOut: &bytes.Buffer{}},
+		)
+
+		assert.Equal(t, expectedErr, cmd.Execute())
+		assert.Equal(t, "Welcome to GitLab, @jane-doe!\n", buffer.String(), "the Go output is shown")
+		assert.Equal(t, "Welcome to GitLab, @jane-doe!\n", cmd.stdout.String(), "the Go output is captured")
+	}
+}
+
+func TestShadowExecuteWithTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shadow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "bin"), 0755))
+	script := "#!/bin/sh\nsleep 10\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bin", fallback.RubyProgram), []byte(script), 0755))
+
+	oldTimeout := shadowTimeout
+	shadowTimeout = 50 * time.Millisecond
+	defer func() { shadowTimeout = oldTimeout }()
+
+	expectedErr := errors.New("Failed")
+	buffer := &bytes.Buffer{}
+	build := func(config *config.Config, args *commandargs.CommandArgs, readWriter *readwriter.ReadWriter) Command {
+		return &fakeCommand{ReadWriter: readWriter, Err: expectedErr}
+	}
+
+	cmd := newShadowCommand(
+		build,
+		[]string{"gitlab-shell", "key-1"},
+		&commandargs.CommandArgs{CommandType: commandargs.Discover},
+		&config.Config{RootDir: dir},
+		&readwriter.ReadWriter{Out: buffer, ErrOut: &bytes.Buffer{}},
+	)
+
+	start := time.Now()
+	assert.Equal(t, expectedErr, cmd.Execute())
+	assert.True(t, time.Since(start) < 5*time.Second, "the Go result doesn't wait for the shadow")
+	assert.Equal(t, "Welcome to GitLab, @jane-doe!\n", buffer.String())
+}
+
+func TestCompareResults(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		goResult            *fallback.Result
+		rubyResult          *fallback.Result
+		expectedDifferences map[string]interface{}
+	}{
+		{
+			desc:       "With the same results",
+			goResult:   &fallback.Result{Stdout: []byte("Welcome\n"), ExitCode: 0},
+			rubyResult: &fallback.Result{Stdout: []byte("Welcome\n"), ExitCode: 0},
+		},
+		{
+			desc:       "With a different output",
+			goResult:   &fallback.Result{Stdout: []byte("Welcome\n\nMaintenance tonight\n"), Stderr: []byte("warning\n")},
+			rubyResult: &fallback.Result{Stdout: []byte("Welcome\n"), Stderr: []byte("WARNING\n")},
+			expectedDifferences: map[string]interface{}{
+				"stdout_diff": "-\n-Maintenance tonight\n",
+				"stderr_diff": "-warning\n+WARNING\n",
+			},
+		},
+		{
+			desc:       "With different exit codes",
+			goResult:   &fallback.Result{ExitCode: 1},
+			rubyResult: &fallback.Result{ExitCode: 0},
+			expectedDifferences: map[string]interface{}{
+				"go_exit_code":   1,
+				"ruby_exit_code": 0,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expectedDifferences, compareResults(tc.goResult, tc.rubyResult))
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Empty(t, diffLines("a\nb\n", "a\nb\n"))
+	assert.Equal(t, "-b\n+c\n", diffLines("a\nb\n", "a\nc\n"))
+	assert.Equal(t, "+b\n", diffLines("a\nc\n", "a\nb\nc\n"))
+	assert.Equal(t, "-a\n", diffLines("a\n", ""))
+	assert.Equal(t, "-a\n+a\n", diffLines("a\n", "a"), "a missing final newline")
+}
@@ -1,13 +1,27 @@
 package twofactorrecover
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/twofactorrecover"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+const (
+	usageText = "Usage: 2fa_recovery_codes [--yes] [--format=text|json]"
+)
+
+var (
+	errCustomAction = errors.New("The GitLab API asked for a custom action, which is not supported")
 )
 
 type Command struct {
@@ -16,26 +30,107 @@ type Command struct {
 	ReadWriter *readwriter.ReadWriter
 }
 
+type options struct {
+	yes  bool
+	json bool
+}
+
+type jsonOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
 func (c *Command) Execute() error {
-	if c.canContinue() {
-		c.displayRecoveryCodes()
-	} else {
-		fmt.Fprintln(c.ReadWriter.Out, "\nNew recovery codes have *not* been generated. Existing codes will remain valid.")
+	opts, err := c.parseOptions()
+	if err != nil {
+		return err
 	}
 
+	// Keep stdout parseable when printing JSON
+	messageOut := c.ReadWriter.Out
+	if opts.json {
+		messageOut = c.ReadWriter.ErrOut
+	}
+
+	if !opts.yes && !c.canContinue(messageOut) {
+		fmt.Fprintln(messageOut, "\nNew recovery codes have *not* been generated. Existing codes will remain valid.")
+		return nil
+	}
+
+	if opts.json {
+		return c.displayRecoveryCodesJSON()
+	}
+
+	c.displayRecoveryCodes()
+
 	return nil
 }
 
-func (c *Command) canContinue() bool {
+// parseOptions reads the options passed after the command name, e.g.
+// `2fa_recovery_codes --yes --format=json`
+func (c *Command) parseOptions() (*options, error) {
+	opts := &options{}
+
+	if len(c.Args.SshArgs) < 2 {
+		return opts, nil
+	}
+
+	for _, arg := range c.Args.SshArgs[1:] {
+		switch arg {
+		case "--yes", "-y":
+			opts.yes = true
+		case "--format=json":
+			opts.json = true
+		case "--format=text":
+			opts.json = false
+		default:
+			return nil, fmt.Errorf("Unknown option: '%s'. %s", arg, usageText)
+		}
+	}
+
+	return opts, nil
+}
+
+func (c *Command) canContinue(out io.Writer) bool {
 	question :=
 		"Are you sure you want to generate new two-factor recovery codes?\n" +
 			"Any existing recovery codes you saved will be invalidated. (yes/no)"
-	fmt.Fprintln(c.ReadWriter.Out, question)
+	fmt.Fprintln(out, question)
+
+	answer, ok := c.readAnswer()
+	if !ok {
+		fmt.Fprintln(out, "\nTimed out waiting for an answer.")
+		return false
+	}
 
-	var answer string
-	fmt.Fscanln(c.ReadWriter.In, &answer)
+	switch strings.ToLower(answer) {
+	case "yes", "y":
+		return true
+	default:
+		return false
+	}
+}
+
+// readAnswer waits for a line of input for at most the configured prompt
+// timeout. It returns false if it timed out.
+func (c *Command) readAnswer() (string, bool) {
+	answers := make(chan string, 1)
+	go func() {
+		var answer string
+		fmt.Fscanln(c.ReadWriter.In, &answer)
+		answers <- answer
+	}()
+
+	timeout := c.Config.PromptTimeout()
+	if timeout == 0 {
+		return <-answers, true
+	}
 
-	return answer == "yes"
+	select {
+	case answer := <-answers:
+		return answer, true
+	case <-time.After(timeout):
+		return "", false
+	}
 }
 
 func (c *Command) displayRecoveryCodes() {
@@ -54,6 +149,15 @@ func (c *Command) displayRecoveryCodes() {
 	}
 }
 
+func (c *Command) displayRecoveryCodesJSON() error {
+	codes, err := c.getRecoveryCodes()
+	if err != nil {
+		return fmt.Errorf("An error occurred while trying to generate new recovery codes: %v", err)
+	}
+
+	return json.NewEncoder(c.ReadWriter.Out).Encode(&jsonOutput{RecoveryCodes: codes})
+}
+
 func (c *Command) getRecoveryCodes() ([]string, error) {
 	client, err := twofactorrecover.NewClient(c.Config)
 
@@ -61,5 +165,14 @@ func (c *Command) getRecoveryCodes() ([]string, error) {
 		return nil, err
 	}
 
-	return client.GetRecoveryCodes(c.Args)
+	codes, err := client.GetRecoveryCodes(c.Args)
+
+	// The Ruby implementation can't run instead: it would prompt again, and
+	// doesn't know the options
+	if gitlabnet.IsCustomAction(err) {
+		logger.Warn("Custom actions are not supported by 2fa_recovery_codes", map[string]interface{}{"user": c.Args.LogUsername()})
+		return nil, errCustomAction
+	}
+
+	return codes, err
 }
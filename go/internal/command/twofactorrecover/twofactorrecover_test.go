@@ -3,8 +3,10 @@ package twofactorrecover
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -49,6 +51,9 @@ func setup(t *testing.T) {
 					json.NewEncoder(w).Encode(body)
 				case "broken":
 					w.WriteHeader(http.StatusInternalServerError)
+				case "custom_action":
+					w.WriteHeader(http.StatusMultipleChoices)
+					json.NewEncoder(w).Encode(map[string]interface{}{})
 				}
 			},
 		},
@@ -76,7 +81,7 @@ func TestExecute(t *testing.T) {
 	}{
 		{
 			desc:      "With a known key id",
-			arguments: &commandargs.CommandArgs{GitlabKeyId: "1"},
+			arguments: &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
 			answer:    "yes\n",
 			expectedOutput: question +
 				"Your two-factor authentication recovery codes are:\n\nrecovery\ncodes\n\n" +
@@ -84,24 +89,49 @@ func TestExecute(t *testing.T) {
 				"your two-factor code. Then, visit your Profile Settings and add\n" +
 				"a new device so you do not lose access to your account again.\n",
 		},
+		{
+			desc:      "With an uppercase short answer",
+			arguments: &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
+			answer:    "Y\n",
+			expectedOutput: question +
+				"Your two-factor authentication recovery codes are:\n\nrecovery\ncodes\n\n" +
+				"During sign in, use one of the codes above when prompted for\n" +
+				"your two-factor code. Then, visit your Profile Settings and add\n" +
+				"a new device so you do not lose access to your account again.\n",
+		},
+		{
+			desc:      "With the yes option",
+			arguments: &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}, SshArgs: []string{"2fa_recovery_codes", "--yes"}},
+			answer:    "",
+			expectedOutput: "\nYour two-factor authentication recovery codes are:\n\nrecovery\ncodes\n\n" +
+				"During sign in, use one of the codes above when prompted for\n" +
+				"your two-factor code. Then, visit your Profile Settings and add\n" +
+				"a new device so you do not lose access to your account again.\n",
+		},
 		{
 			desc:           "With bad response",
-			arguments:      &commandargs.CommandArgs{GitlabKeyId: "-1"},
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "-1"}},
 			answer:         "yes\n",
 			expectedOutput: question + errorHeader + "Parsing failed\n",
 		},
 		{
 			desc:           "With API returns an error",
-			arguments:      &commandargs.CommandArgs{GitlabKeyId: "forbidden"},
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "forbidden"}},
 			answer:         "yes\n",
 			expectedOutput: question + errorHeader + "Forbidden!\n",
 		},
 		{
 			desc:           "With API fails",
-			arguments:      &commandargs.CommandArgs{GitlabKeyId: "broken"},
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "broken"}},
 			answer:         "yes\n",
 			expectedOutput: question + errorHeader + "Internal API error (500)\n",
 		},
+		{
+			desc:           "With API asking for a custom action",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "custom_action"}},
+			answer:         "yes\n",
+			expectedOutput: question + errorHeader + "The GitLab API asked for a custom action, which is not supported\n",
+		},
 		{
 			desc:           "With missing arguments",
 			arguments:      &commandargs.CommandArgs{},
@@ -135,3 +165,97 @@ func TestExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteJSON(t *testing.T) {
+	setup(t)
+
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	testCases := []struct {
+		desc           string
+		arguments      *commandargs.CommandArgs
+		answer         string
+		expectedOutput string
+		expectedStderr string
+		expectedError  string
+	}{
+		{
+			desc:           "With the yes option",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}, SshArgs: []string{"2fa_recovery_codes", "--yes", "--format=json"}},
+			expectedOutput: "{\"recovery_codes\":[\"recovery\",\"codes\"]}\n",
+		},
+		{
+			desc:           "With a positive answer",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}, SshArgs: []string{"2fa_recovery_codes", "--format=json"}},
+			answer:         "yes\n",
+			expectedOutput: "{\"recovery_codes\":[\"recovery\",\"codes\"]}\n",
+			expectedStderr: strings.TrimSuffix(question, "\n"),
+		},
+		{
+			desc:           "With a negative answer",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}, SshArgs: []string{"2fa_recovery_codes", "--format=json"}},
+			answer:         "no\n",
+			expectedStderr: question + "New recovery codes have *not* been generated. Existing codes will remain valid.\n",
+		},
+		{
+			desc:          "With API returns an error",
+			arguments:     &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "forbidden"}, SshArgs: []string{"2fa_recovery_codes", "--yes", "--format=json"}},
+			expectedError: "An error occurred while trying to generate new recovery codes: Forbidden!",
+		},
+		{
+			desc:          "With API asking for a custom action",
+			arguments:     &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "custom_action"}, SshArgs: []string{"2fa_recovery_codes", "--yes", "--format=json"}},
+			expectedError: "An error occurred while trying to generate new recovery codes: The GitLab API asked for a custom action, which is not supported",
+		},
+		{
+			desc:          "With an unknown option",
+			arguments:     &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}, SshArgs: []string{"2fa_recovery_codes", "--no"}},
+			expectedError: "Unknown option: '--no'. Usage: 2fa_recovery_codes [--yes] [--format=text|json]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			input := bytes.NewBufferString(tc.answer)
+
+			cmd := &Command{
+				Config:     &config.Config{GitlabUrl: url},
+				Args:       tc.arguments,
+				ReadWriter: &readwriter.ReadWriter{Out: output, ErrOut: stderr, In: input},
+			}
+
+			err := cmd.Execute()
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+
+			assert.Equal(t, tc.expectedOutput, output.String())
+			assert.Equal(t, tc.expectedStderr, stderr.String())
+		})
+	}
+}
+
+func TestPromptTimeout(t *testing.T) {
+	output := &bytes.Buffer{}
+	input, writer := io.Pipe()
+	defer writer.Close()
+
+	cmd := &Command{
+		Config:     &config.Config{PromptTimeoutSeconds: 1},
+		Args:       &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
+		ReadWriter: &readwriter.ReadWriter{Out: output, In: input},
+	}
+
+	err := cmd.Execute()
+
+	assert.NoError(t, err)
+	assert.Equal(t, question+"Timed out waiting for an answer.\n\n"+
+		"New recovery codes have *not* been generated. Existing codes will remain valid.\n", output.String())
+}
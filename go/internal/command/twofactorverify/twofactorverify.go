@@ -0,0 +1,59 @@
+package twofactorverify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/twofactorverify"
+)
+
+const (
+	// maxOTPLength bounds what we read from the user, one-time passwords are
+	// only a few characters long
+	maxOTPLength = 64
+)
+
+type Command struct {
+	Config     *config.Config
+	Args       *commandargs.CommandArgs
+	ReadWriter *readwriter.ReadWriter
+}
+
+// Execute returns an error when the validation fails, so the process exits
+// with a non-zero status
+func (c *Command) Execute() error {
+	otp := c.getOTP()
+	if otp == "" {
+		return errors.New("OTP validation failed: No OTP was entered")
+	}
+
+	if err := c.verifyOTP(otp); err != nil {
+		return fmt.Errorf("OTP validation failed: %v", err)
+	}
+
+	fmt.Fprintln(c.ReadWriter.Out, "\nOTP validation successful. Git operations are now allowed.")
+
+	return nil
+}
+
+func (c *Command) getOTP() string {
+	fmt.Fprint(c.ReadWriter.Out, "OTP: ")
+
+	var answer string
+	fmt.Fscanln(io.LimitReader(c.ReadWriter.In, maxOTPLength), &answer)
+
+	return answer
+}
+
+func (c *Command) verifyOTP(otp string) error {
+	client, err := twofactorverify.NewClient(c.Config)
+	if err != nil {
+		return err
+	}
+
+	return client.VerifyOTP(c.Args, otp)
+}
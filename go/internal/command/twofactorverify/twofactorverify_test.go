@@ -0,0 +1,129 @@
+package twofactorverify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/testserver"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/twofactorverify"
+)
+
+var (
+	requests []testserver.TestRequestHandler
+)
+
+func setup(t *testing.T) {
+	requests = []testserver.TestRequestHandler{
+		{
+			Path: "/api/v4/internal/two_factor_otp_check",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				defer r.Body.Close()
+
+				require.NoError(t, err)
+
+				var requestBody *twofactorverify.RequestBody
+				json.Unmarshal(b, &requestBody)
+
+				switch requestBody.KeyId {
+				case "1":
+					body := map[string]interface{}{
+						"success": requestBody.OTPAttempt == "123456",
+						"message": "Invalid OTP",
+					}
+					json.NewEncoder(w).Encode(body)
+				case "broken":
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			},
+		},
+	}
+}
+
+const (
+	question = "OTP: "
+)
+
+func TestExecute(t *testing.T) {
+	setup(t)
+
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	testCases := []struct {
+		desc           string
+		arguments      *commandargs.CommandArgs
+		answer         string
+		expectedOutput string
+		expectedError  string
+	}{
+		{
+			desc:           "With a valid OTP",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
+			answer:         "123456\n",
+			expectedOutput: question + "\nOTP validation successful. Git operations are now allowed.\n",
+		},
+		{
+			desc:           "With an invalid OTP",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
+			answer:         "654321\n",
+			expectedOutput: question,
+			expectedError:  "OTP validation failed: Invalid OTP",
+		},
+		{
+			desc:           "Without an OTP",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"}},
+			answer:         "\n",
+			expectedOutput: question,
+			expectedError:  "OTP validation failed: No OTP was entered",
+		},
+		{
+			desc:           "With API fails",
+			arguments:      &commandargs.CommandArgs{Who: &commandargs.Who{Kind: commandargs.WhoKey, Value: "broken"}},
+			answer:         "123456\n",
+			expectedOutput: question,
+			expectedError:  "OTP validation failed: Internal API error (500)",
+		},
+		{
+			desc:           "With missing arguments",
+			arguments:      &commandargs.CommandArgs{},
+			answer:         "123456\n",
+			expectedOutput: question,
+			expectedError:  "OTP validation failed: who='' is invalid",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			input := bytes.NewBufferString(tc.answer)
+
+			cmd := &Command{
+				Config:     &config.Config{GitlabUrl: url},
+				Args:       tc.arguments,
+				ReadWriter: &readwriter.ReadWriter{Out: output, In: input},
+			}
+
+			err := cmd.Execute()
+
+			// main exits with a non-zero status on errors
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+
+			assert.Equal(t, tc.expectedOutput, output.String())
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -14,11 +16,48 @@ const (
 	configFile            = "config.yml"
 	logFile               = "gitlab-shell.log"
 	defaultSecretFileName = ".gitlab_shell_secret"
+	defaultLockDir        = "tmp/concurrency"
+
+	defaultCommandDisabledMessage = "This command has been disabled by your administrator."
+
+	defaultCacheDir                = "tmp/cache"
+	defaultAnnouncementsTTLSeconds = 60
+
+	defaultAuthorizedKeysCacheTTLSeconds = 300
+	defaultAuthorizedKeysCacheMaxEntries = 10000
+
+	defaultDenylistFile    = "denylist"
+	defaultDenylistMessage = "Your access to this server has been blocked. Contact your administrator."
+
+	defaultMaintenanceFlagFile = "maintenance"
+	defaultMaintenanceMessage  = "This server is in read-only mode for maintenance. Pushes are disabled, please try again later."
 )
 
 type MigrationConfig struct {
-	Enabled  bool     `yaml:"enabled"`
-	Features []string `yaml:"features"`
+	Enabled  bool                     `yaml:"enabled"`
+	Features []string                 `yaml:"features"`
+	Rollout  map[string]RolloutConfig `yaml:"rollout"`
+	// Shadow lists the features whose Ruby implementation also runs, with
+	// its output compared to the Go one
+	Shadow []string `yaml:"shadow"`
+}
+
+// RolloutConfig limits an enabled migration feature to some sessions.
+// Sessions of the listed KeyIds and Usernames use the Go implementation, and
+// Percentage of the other sessions do. When there are Windows, sessions
+// outside of them all use the Ruby implementation.
+type RolloutConfig struct {
+	Percentage uint                  `yaml:"percentage"`
+	KeyIds     []string              `yaml:"key_ids"`
+	Usernames  []string              `yaml:"usernames"`
+	Windows    []RolloutWindowConfig `yaml:"windows"`
+}
+
+// RolloutWindowConfig is a period of time, with RFC 3339 bounds. Either
+// bound can be left out.
+type RolloutWindowConfig struct {
+	StartsAt string `yaml:"starts_at"`
+	EndsAt   string `yaml:"ends_at"`
 }
 
 type HttpSettingsConfig struct {
@@ -30,17 +69,111 @@ type HttpSettingsConfig struct {
 	SelfSignedCert     bool   `yaml:"self_signed_cert"`
 }
 
+// GitTimeoutsConfig limits how long git operations proxied to Gitaly may
+// take. Values are in seconds, zero disables the timeout. MaxDurationSeconds
+// is keyed by git command, e.g. git-upload-pack.
+type GitTimeoutsConfig struct {
+	IdleTimeoutSeconds uint64            `yaml:"idle_timeout"`
+	MaxDurationSeconds map[string]uint64 `yaml:"max_duration"`
+}
+
+// RateLimitConfig limits the bandwidth of a git operation in bytes per
+// second, zero means unlimited. Upload is the data sent by the client,
+// download the data sent to it.
+type RateLimitConfig struct {
+	UploadBytesPerSecond   uint64 `yaml:"upload_bytes_per_second"`
+	DownloadBytesPerSecond uint64 `yaml:"download_bytes_per_second"`
+}
+
+// ConcurrencyLimitsConfig limits the number of git operations a user or a
+// repository can run at the same time on this host, zero means unlimited.
+// Operations over the limit wait up to QueueTimeoutSeconds for a slot.
+type ConcurrencyLimitsConfig struct {
+	LockDir             string `yaml:"lock_dir"`
+	PerUser             int    `yaml:"per_user"`
+	PerRepository       int    `yaml:"per_repository"`
+	QueueTimeoutSeconds uint64 `yaml:"queue_timeout"`
+}
+
+// CommandConfig enables or disables a command for this installation.
+// Commands are enabled unless Enabled is set to false.
+type CommandConfig struct {
+	Enabled *bool  `yaml:"enabled"`
+	Message string `yaml:"message"`
+}
+
+// MaintenanceConfig puts this host in read-only mode, rejecting pushes while
+// still allowing fetches. The mode is on when Enabled is set or when FlagFile
+// exists, so it can be switched without editing the config.
+type MaintenanceConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	FlagFile string `yaml:"flag_file"`
+	Message  string `yaml:"message"`
+}
+
+// DenylistConfig points to the local file of keys, users and networks denied
+// access, see the denylist package for its format
+type DenylistConfig struct {
+	File    string `yaml:"file"`
+	Message string `yaml:"message"`
+}
+
+// AnnouncementsConfig controls the messages shown to users running
+// `ssh git@gitlab.example.com`: the active GitLab broadcast messages, unless
+// BroadcastMessages is set to false, and the content of MotdFile. They are
+// cached for CacheTTLSeconds.
+type AnnouncementsConfig struct {
+	BroadcastMessages *bool  `yaml:"broadcast_messages"`
+	MotdFile          string `yaml:"motd_file"`
+	CacheTTLSeconds   uint64 `yaml:"cache_ttl"`
+}
+
+// AuthorizedKeysCacheConfig caches the keys looked up by
+// gitlab-shell-authorized-keys-check in CacheDir. A cached key is used
+// without calling the API for TTLSeconds, and for as long as the API fails
+// after that.
+type AuthorizedKeysCacheConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	TTLSeconds uint64 `yaml:"ttl"`
+	MaxEntries int    `yaml:"max_entries"`
+}
+
+// KeyOptionsConfig sets the options of the authorized_keys lines generated in
+// Go. Restrict uses OpenSSH's restrict keyword instead of the no-* options.
+// From restricts the addresses keys can be used from, and Environment sets
+// NAME=value variables, which needs PermitUserEnvironment in sshd. With
+// ExpiryTime, lines of keys expiring in GitLab get an expiry-time.
+type KeyOptionsConfig struct {
+	Restrict    bool     `yaml:"restrict"`
+	From        []string `yaml:"from"`
+	Environment []string `yaml:"environment"`
+	ExpiryTime  bool     `yaml:"expiry_time"`
+}
+
 type Config struct {
-	RootDir        string
-	LogFile        string             `yaml:"log_file"`
-	LogFormat      string             `yaml:"log_format"`
-	Migration      MigrationConfig    `yaml:"migration"`
-	GitlabUrl      string             `yaml:"gitlab_url"`
-	GitlabTracing  string             `yaml:"gitlab_tracing"`
-	SecretFilePath string             `yaml:"secret_file"`
-	Secret         string             `yaml:"secret"`
-	HttpSettings   HttpSettingsConfig `yaml:"http_settings"`
-	HttpClient     *HttpClient
+	RootDir              string
+	LogFile              string                     `yaml:"log_file"`
+	LogFormat            string                     `yaml:"log_format"`
+	Migration            MigrationConfig            `yaml:"migration"`
+	GitlabUrl            string                     `yaml:"gitlab_url"`
+	GitlabTracing        string                     `yaml:"gitlab_tracing"`
+	SecretFilePath       string                     `yaml:"secret_file"`
+	Secret               string                     `yaml:"secret"`
+	HttpSettings         HttpSettingsConfig         `yaml:"http_settings"`
+	GitTimeouts          GitTimeoutsConfig          `yaml:"git_timeouts"`
+	GitRateLimits        map[string]RateLimitConfig `yaml:"git_rate_limits"`
+	Concurrency          ConcurrencyLimitsConfig    `yaml:"concurrency_limits"`
+	PromptTimeoutSeconds uint64                     `yaml:"prompt_timeout"`
+	Commands             map[string]CommandConfig   `yaml:"commands"`
+	Maintenance          MaintenanceConfig          `yaml:"maintenance"`
+	Denylist             DenylistConfig             `yaml:"denylist"`
+	CacheDir             string                     `yaml:"cache_dir"`
+	Announcements        AnnouncementsConfig        `yaml:"announcements"`
+	AuthorizedKeysCache  AuthorizedKeysCacheConfig  `yaml:"authorized_keys_cache"`
+	KeyOptions           KeyOptionsConfig           `yaml:"authorized_keys_options"`
+	AuthFile             string                     `yaml:"auth_file"`
+	AuthFileFallback     bool                       `yaml:"auth_file_fallback"`
+	HttpClient           *HttpClient
 }
 
 func New() (*Config, error) {
@@ -53,7 +186,13 @@ func New() (*Config, error) {
 }
 
 func NewFromDir(dir string) (*Config, error) {
-	return newFromFile(path.Join(dir, configFile))
+	return newFromFile(dir, path.Join(dir, configFile))
+}
+
+// NewFromPaths reads the config file found by FindPaths. Relative paths in
+// it are relative to the root directory, wherever the file is.
+func NewFromPaths(paths *Paths) (*Config, error) {
+	return newFromFile(paths.RootDir, paths.ConfigFile)
 }
 
 func (c *Config) FeatureEnabled(featureName string) bool {
@@ -70,8 +209,153 @@ func (c *Config) FeatureEnabled(featureName string) bool {
 	return false
 }
 
-func newFromFile(filename string) (*Config, error) {
-	cfg := &Config{RootDir: path.Dir(filename)}
+// FeatureShadowed tells whether the Ruby implementation of a feature runs in
+// the shadow of the Go one
+func (c *Config) FeatureShadowed(featureName string) bool {
+	for _, shadowedFeature := range c.Migration.Shadow {
+		if shadowedFeature == featureName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FeatureRollout returns the rollout rules of a migration feature, or nil
+// when it applies to every session
+func (c *Config) FeatureRollout(featureName string) *RolloutConfig {
+	rollout, ok := c.Migration.Rollout[featureName]
+	if !ok {
+		return nil
+	}
+
+	return &rollout
+}
+
+// InWindow tells whether t is in one of the windows, or there are none
+func (r *RolloutConfig) InWindow(t time.Time) bool {
+	if len(r.Windows) == 0 {
+		return true
+	}
+
+	for _, window := range r.Windows {
+		// The windows are validated when the config is parsed
+		startsAt, endsAt, _ := window.bounds()
+
+		if (startsAt.IsZero() || !t.Before(startsAt)) && (endsAt.IsZero() || t.Before(endsAt)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w RolloutWindowConfig) bounds() (time.Time, time.Time, error) {
+	var startsAt, endsAt time.Time
+	var err error
+
+	if w.StartsAt != "" {
+		if startsAt, err = time.Parse(time.RFC3339, w.StartsAt); err != nil {
+			return startsAt, endsAt, err
+		}
+	}
+
+	if w.EndsAt != "" {
+		if endsAt, err = time.Parse(time.RFC3339, w.EndsAt); err != nil {
+			return startsAt, endsAt, err
+		}
+	}
+
+	return startsAt, endsAt, nil
+}
+
+// CommandEnabled tells whether users may run a command, e.g.
+// git-upload-archive. Commands are keyed by their name, "discover" is the
+// command run without arguments.
+func (c *Config) CommandEnabled(commandName string) bool {
+	command, ok := c.Commands[commandName]
+	if !ok || command.Enabled == nil {
+		return true
+	}
+
+	return *command.Enabled
+}
+
+// CommandDisabledMessage is shown to users running a disabled command
+func (c *Config) CommandDisabledMessage(commandName string) string {
+	if message := c.Commands[commandName].Message; message != "" {
+		return message
+	}
+
+	return defaultCommandDisabledMessage
+}
+
+// ReadOnly tells whether this host is in maintenance mode
+func (c *Config) ReadOnly() bool {
+	if c.Maintenance.Enabled {
+		return true
+	}
+
+	_, err := os.Stat(c.Maintenance.FlagFile)
+
+	return err == nil
+}
+
+// ReadOnlyMessage is shown to users pushing while in maintenance mode
+func (c *Config) ReadOnlyMessage() string {
+	if c.Maintenance.Message != "" {
+		return c.Maintenance.Message
+	}
+
+	return defaultMaintenanceMessage
+}
+
+// DenylistMessage is shown to users on the local denylist
+func (c *Config) DenylistMessage() string {
+	if c.Denylist.Message != "" {
+		return c.Denylist.Message
+	}
+
+	return defaultDenylistMessage
+}
+
+func (c *Config) BroadcastMessagesEnabled() bool {
+	return c.Announcements.BroadcastMessages == nil || *c.Announcements.BroadcastMessages
+}
+
+func (c *Config) AnnouncementsTTL() time.Duration {
+	return time.Duration(c.Announcements.CacheTTLSeconds) * time.Second
+}
+
+func (c *Config) AuthorizedKeysCacheTTL() time.Duration {
+	return time.Duration(c.AuthorizedKeysCache.TTLSeconds) * time.Second
+}
+
+func (c *Config) IdleTimeout() time.Duration {
+	return time.Duration(c.GitTimeouts.IdleTimeoutSeconds) * time.Second
+}
+
+func (c *Config) MaxDuration(gitCommand string) time.Duration {
+	return time.Duration(c.GitTimeouts.MaxDurationSeconds[gitCommand]) * time.Second
+}
+
+// RateLimit returns the rate limit for a git command, e.g. git-upload-pack
+func (c *Config) RateLimit(gitCommand string) RateLimitConfig {
+	return c.GitRateLimits[gitCommand]
+}
+
+// PromptTimeout is how long commands wait for the user to answer a question,
+// zero means forever
+func (c *Config) PromptTimeout() time.Duration {
+	return time.Duration(c.PromptTimeoutSeconds) * time.Second
+}
+
+func (c *Config) QueueTimeout() time.Duration {
+	return time.Duration(c.Concurrency.QueueTimeoutSeconds) * time.Second
+}
+
+func newFromFile(rootDir, filename string) (*Config, error) {
+	cfg := &Config{RootDir: rootDir}
 
 	configBytes, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -99,10 +383,74 @@ func parseConfig(configBytes []byte, cfg *Config) error {
 		cfg.LogFile = path.Join(cfg.RootDir, cfg.LogFile)
 	}
 
+	if cfg.Concurrency.LockDir == "" {
+		cfg.Concurrency.LockDir = defaultLockDir
+	}
+
+	if !filepath.IsAbs(cfg.Concurrency.LockDir) {
+		cfg.Concurrency.LockDir = path.Join(cfg.RootDir, cfg.Concurrency.LockDir)
+	}
+
+	if cfg.Maintenance.FlagFile == "" {
+		cfg.Maintenance.FlagFile = defaultMaintenanceFlagFile
+	}
+
+	if !filepath.IsAbs(cfg.Maintenance.FlagFile) {
+		cfg.Maintenance.FlagFile = path.Join(cfg.RootDir, cfg.Maintenance.FlagFile)
+	}
+
+	if cfg.Denylist.File == "" {
+		cfg.Denylist.File = defaultDenylistFile
+	}
+
+	if !filepath.IsAbs(cfg.Denylist.File) {
+		cfg.Denylist.File = path.Join(cfg.RootDir, cfg.Denylist.File)
+	}
+
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = defaultCacheDir
+	}
+
+	if !filepath.IsAbs(cfg.CacheDir) {
+		cfg.CacheDir = path.Join(cfg.RootDir, cfg.CacheDir)
+	}
+
+	if cfg.Announcements.CacheTTLSeconds == 0 {
+		cfg.Announcements.CacheTTLSeconds = defaultAnnouncementsTTLSeconds
+	}
+
+	if cfg.AuthFile == "" {
+		cfg.AuthFile = path.Join(os.Getenv("HOME"), ".ssh/authorized_keys")
+	}
+
+	if cfg.AuthorizedKeysCache.TTLSeconds == 0 {
+		cfg.AuthorizedKeysCache.TTLSeconds = defaultAuthorizedKeysCacheTTLSeconds
+	}
+
+	if cfg.AuthorizedKeysCache.MaxEntries == 0 {
+		cfg.AuthorizedKeysCache.MaxEntries = defaultAuthorizedKeysCacheMaxEntries
+	}
+
+	if cfg.Announcements.MotdFile != "" && !filepath.IsAbs(cfg.Announcements.MotdFile) {
+		cfg.Announcements.MotdFile = path.Join(cfg.RootDir, cfg.Announcements.MotdFile)
+	}
+
 	if cfg.LogFormat == "" {
 		cfg.LogFormat = "text"
 	}
 
+	for featureName, rollout := range cfg.Migration.Rollout {
+		if rollout.Percentage > 100 {
+			return fmt.Errorf("invalid rollout percentage for %s: %d", featureName, rollout.Percentage)
+		}
+
+		for _, window := range rollout.Windows {
+			if _, _, err := window.bounds(); err != nil {
+				return fmt.Errorf("invalid rollout window for %s: %v", featureName, err)
+			}
+		}
+	}
+
 	if cfg.GitlabUrl != "" {
 		unescapedUrl, err := url.PathUnescape(cfg.GitlabUrl)
 		if err != nil {
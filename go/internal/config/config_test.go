@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -121,6 +124,215 @@ func TestParseConfig(t *testing.T) {
 	}
 }
 
+func TestGitTimeouts(t *testing.T) {
+	cfg := Config{RootDir: testRoot, Secret: "secret"}
+
+	yaml := "git_timeouts:\n  idle_timeout: 300\n  max_duration:\n    git-upload-pack: 3600"
+	require.NoError(t, parseConfig([]byte(yaml), &cfg))
+
+	assert.Equal(t, 300*time.Second, cfg.IdleTimeout())
+	assert.Equal(t, time.Hour, cfg.MaxDuration("git-upload-pack"))
+	assert.Equal(t, time.Duration(0), cfg.MaxDuration("git-receive-pack"))
+}
+
+func TestGitRateLimits(t *testing.T) {
+	cfg := Config{RootDir: testRoot, Secret: "secret"}
+
+	yaml := "git_rate_limits:\n  git-upload-pack:\n    upload_bytes_per_second: 1024\n    download_bytes_per_second: 2048"
+	require.NoError(t, parseConfig([]byte(yaml), &cfg))
+
+	assert.Equal(t, RateLimitConfig{UploadBytesPerSecond: 1024, DownloadBytesPerSecond: 2048}, cfg.RateLimit("git-upload-pack"))
+	assert.Equal(t, RateLimitConfig{}, cfg.RateLimit("git-receive-pack"))
+}
+
+func TestConcurrencyLimits(t *testing.T) {
+	testCases := []struct {
+		yaml     string
+		expected ConcurrencyLimitsConfig
+	}{
+		{
+			expected: ConcurrencyLimitsConfig{LockDir: path.Join(testRoot, "tmp/concurrency")},
+		},
+		{
+			yaml:     "concurrency_limits:\n  lock_dir: /run/gitlab-shell\n  per_user: 5\n  per_repository: 10\n  queue_timeout: 30",
+			expected: ConcurrencyLimitsConfig{LockDir: "/run/gitlab-shell", PerUser: 5, PerRepository: 10, QueueTimeoutSeconds: 30},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("yaml input: %q", tc.yaml), func(t *testing.T) {
+			cfg := Config{RootDir: testRoot, Secret: "secret"}
+			require.NoError(t, parseConfig([]byte(tc.yaml), &cfg))
+
+			assert.Equal(t, tc.expected, cfg.Concurrency)
+		})
+	}
+}
+
+func TestAnnouncements(t *testing.T) {
+	testCases := []struct {
+		yaml                   string
+		expectedCacheDir       string
+		expectedMotdFile       string
+		expectedTTL            time.Duration
+		expectBroadcastEnabled bool
+	}{
+		{
+			expectedCacheDir:       path.Join(testRoot, "tmp/cache"),
+			expectedTTL:            time.Minute,
+			expectBroadcastEnabled: true,
+		},
+		{
+			yaml:                   "cache_dir: /var/cache/gitlab-shell\nannouncements:\n  broadcast_messages: false\n  motd_file: motd\n  cache_ttl: 5",
+			expectedCacheDir:       "/var/cache/gitlab-shell",
+			expectedMotdFile:       path.Join(testRoot, "motd"),
+			expectedTTL:            5 * time.Second,
+			expectBroadcastEnabled: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("yaml input: %q", tc.yaml), func(t *testing.T) {
+			cfg := Config{RootDir: testRoot, Secret: "secret"}
+			require.NoError(t, parseConfig([]byte(tc.yaml), &cfg))
+
+			assert.Equal(t, tc.expectedCacheDir, cfg.CacheDir)
+			assert.Equal(t, tc.expectedMotdFile, cfg.Announcements.MotdFile)
+			assert.Equal(t, tc.expectedTTL, cfg.AnnouncementsTTL())
+			assert.Equal(t, tc.expectBroadcastEnabled, cfg.BroadcastMessagesEnabled())
+		})
+	}
+}
+
+func TestDenylist(t *testing.T) {
+	testCases := []struct {
+		yaml            string
+		expectedFile    string
+		expectedMessage string
+	}{
+		{
+			expectedFile:    path.Join(testRoot, "denylist"),
+			expectedMessage: defaultDenylistMessage,
+		},
+		{
+			yaml:            "denylist:\n  file: /etc/gitlab-shell/denylist\n  message: Blocked",
+			expectedFile:    "/etc/gitlab-shell/denylist",
+			expectedMessage: "Blocked",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("yaml input: %q", tc.yaml), func(t *testing.T) {
+			cfg := Config{RootDir: testRoot, Secret: "secret"}
+			require.NoError(t, parseConfig([]byte(tc.yaml), &cfg))
+
+			assert.Equal(t, tc.expectedFile, cfg.Denylist.File)
+			assert.Equal(t, tc.expectedMessage, cfg.DenylistMessage())
+		})
+	}
+}
+
+func TestAuthorizedKeysCache(t *testing.T) {
+	testCases := []struct {
+		yaml     string
+		expected AuthorizedKeysCacheConfig
+	}{
+		{
+			expected: AuthorizedKeysCacheConfig{TTLSeconds: 300, MaxEntries: 10000},
+		},
+		{
+			yaml:     "authorized_keys_cache:\n  enabled: true\n  ttl: 60\n  max_entries: 100",
+			expected: AuthorizedKeysCacheConfig{Enabled: true, TTLSeconds: 60, MaxEntries: 100},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("yaml input: %q", tc.yaml), func(t *testing.T) {
+			cfg := Config{RootDir: testRoot, Secret: "secret"}
+			require.NoError(t, parseConfig([]byte(tc.yaml), &cfg))
+
+			assert.Equal(t, tc.expected, cfg.AuthorizedKeysCache)
+		})
+	}
+}
+
+func TestAuthFile(t *testing.T) {
+	restoreEnv := testhelper.TempEnv(map[string]string{"HOME": "/home/git"})
+	defer restoreEnv()
+
+	cfg := Config{RootDir: testRoot, Secret: "secret"}
+	require.NoError(t, parseConfig([]byte(""), &cfg))
+	assert.Equal(t, "/home/git/.ssh/authorized_keys", cfg.AuthFile)
+	assert.False(t, cfg.AuthFileFallback)
+
+	cfg = Config{RootDir: testRoot, Secret: "secret"}
+	require.NoError(t, parseConfig([]byte("auth_file: /var/opt/authorized_keys\nauth_file_fallback: true"), &cfg))
+	assert.Equal(t, "/var/opt/authorized_keys", cfg.AuthFile)
+	assert.True(t, cfg.AuthFileFallback)
+}
+
+func TestReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maintenance")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("It is disabled by default", func(t *testing.T) {
+		cfg := Config{RootDir: dir, Secret: "secret"}
+		require.NoError(t, parseConfig([]byte(""), &cfg))
+
+		assert.Equal(t, path.Join(dir, "maintenance"), cfg.Maintenance.FlagFile)
+		assert.False(t, cfg.ReadOnly())
+		assert.Equal(t, defaultMaintenanceMessage, cfg.ReadOnlyMessage())
+	})
+
+	t.Run("It is enabled in the config", func(t *testing.T) {
+		cfg := Config{RootDir: dir, Secret: "secret"}
+		require.NoError(t, parseConfig([]byte("maintenance:\n  enabled: true\n  message: Migrating storage"), &cfg))
+
+		assert.True(t, cfg.ReadOnly())
+		assert.Equal(t, "Migrating storage", cfg.ReadOnlyMessage())
+	})
+
+	t.Run("It is enabled by the flag file", func(t *testing.T) {
+		cfg := Config{RootDir: dir, Secret: "secret"}
+		require.NoError(t, parseConfig([]byte("maintenance:\n  flag_file: read-only"), &cfg))
+
+		flagFile := path.Join(dir, "read-only")
+		require.NoError(t, ioutil.WriteFile(flagFile, nil, 0644))
+		defer os.Remove(flagFile)
+
+		assert.True(t, cfg.ReadOnly())
+	})
+}
+
+func TestCommandEnabled(t *testing.T) {
+	yaml := "commands:\n" +
+		"  git-upload-archive:\n    enabled: false\n    message: Archives are disabled\n" +
+		"  2fa_recovery_codes:\n    enabled: false\n" +
+		"  git-upload-pack:\n    enabled: true\n"
+
+	cfg := Config{RootDir: testRoot, Secret: "secret"}
+	require.NoError(t, parseConfig([]byte(yaml), &cfg))
+
+	testCases := []struct {
+		command         string
+		expectEnabled   bool
+		expectedMessage string
+	}{
+		{command: "git-upload-archive", expectEnabled: false, expectedMessage: "Archives are disabled"},
+		{command: "2fa_recovery_codes", expectEnabled: false, expectedMessage: defaultCommandDisabledMessage},
+		{command: "git-upload-pack", expectEnabled: true, expectedMessage: defaultCommandDisabledMessage},
+		{command: "discover", expectEnabled: true, expectedMessage: defaultCommandDisabledMessage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.command, func(t *testing.T) {
+			assert.Equal(t, tc.expectEnabled, cfg.CommandEnabled(tc.command))
+			assert.Equal(t, tc.expectedMessage, cfg.CommandDisabledMessage(tc.command))
+		})
+	}
+}
+
 func TestFeatureEnabled(t *testing.T) {
 	testCases := []struct {
 		desc          string
@@ -181,3 +393,77 @@ func TestFeatureEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureRollout(t *testing.T) {
+	yaml := "migration:\n" +
+		"  enabled: true\n" +
+		"  features: [discover, help]\n" +
+		"  rollout:\n" +
+		"    discover:\n" +
+		"      percentage: 10\n" +
+		"      key_ids: ['1']\n" +
+		"      usernames: [jane-doe]\n" +
+		"      windows:\n" +
+		"        - starts_at: '2026-10-19T08:00:00Z'\n" +
+		"          ends_at: '2026-10-19T18:00:00Z'\n" +
+		"        - starts_at: '2026-10-26T08:00:00Z'\n"
+
+	cfg := Config{RootDir: testRoot, Secret: "secret"}
+	require.NoError(t, parseConfig([]byte(yaml), &cfg))
+
+	assert.Nil(t, cfg.FeatureRollout("help"))
+
+	rollout := cfg.FeatureRollout("discover")
+	require.NotNil(t, rollout)
+	assert.Equal(t, uint(10), rollout.Percentage)
+	assert.Equal(t, []string{"1"}, rollout.KeyIds)
+	assert.Equal(t, []string{"jane-doe"}, rollout.Usernames)
+
+	for when, expected := range map[string]bool{
+		"2026-10-19T07:59:59Z": false,
+		"2026-10-19T08:00:00Z": true,
+		"2026-10-19T17:59:59Z": true,
+		"2026-10-19T18:00:00Z": false,
+		"2026-10-26T08:00:00Z": true,
+		"2027-01-01T00:00:00Z": true,
+	} {
+		now, err := time.Parse(time.RFC3339, when)
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, rollout.InWindow(now), when)
+	}
+
+	assert.True(t, (&RolloutConfig{}).InWindow(time.Now()), "without windows")
+}
+
+func TestFeatureShadowed(t *testing.T) {
+	cfg := Config{RootDir: testRoot, Secret: "secret"}
+	require.NoError(t, parseConfig([]byte("migration:\n  shadow: [discover]"), &cfg))
+
+	assert.True(t, cfg.FeatureShadowed("discover"))
+	assert.False(t, cfg.FeatureShadowed("help"))
+}
+
+func TestInvalidFeatureRollout(t *testing.T) {
+	testCases := []struct {
+		yaml          string
+		expectedError string
+	}{
+		{
+			yaml:          "migration:\n  rollout:\n    discover:\n      percentage: 101",
+			expectedError: "invalid rollout percentage for discover: 101",
+		},
+		{
+			yaml:          "migration:\n  rollout:\n    discover:\n      windows:\n        - ends_at: tomorrow",
+			expectedError: `invalid rollout window for discover: parsing time "tomorrow" as "2006-01-02T15:04:05Z07:00": cannot parse "tomorrow" as "2006"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expectedError, func(t *testing.T) {
+			cfg := Config{RootDir: testRoot, Secret: "secret"}
+
+			assert.EqualError(t, parseConfig([]byte(tc.yaml), &cfg), tc.expectedError)
+		})
+	}
+}
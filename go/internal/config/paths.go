@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// RootDirEnv and ConfigFileEnv override where gitlab-shell is installed
+	// and where its config file is. The Ruby implementation reads them too.
+	RootDirEnv    = "GITLAB_SHELL_DIR"
+	ConfigFileEnv = "GITLAB_SHELL_CONFIG"
+
+	// RootDirFlag and ConfigFileFlag override the environment variables,
+	// e.g. `gitlab-shell --config=/etc/gitlab-shell/config.yml key-1`
+	RootDirFlag    = "--root-dir"
+	ConfigFileFlag = "--config"
+
+	compiledInSource = "compiled-in default"
+	heuristicSource  = "heuristic"
+	rootDirSource    = "root directory"
+)
+
+var (
+	// DefaultRootDir and DefaultConfigFile are compiled in by packages with
+	// another layout, e.g. with -ldflags "-X
+	// gitlab.com/gitlab-org/gitlab-shell/go/internal/config.DefaultConfigFile=/etc/gitlab-shell/config.yml"
+	DefaultRootDir    string
+	DefaultConfigFile string
+
+	// executableFunc is overridden in tests
+	executableFunc = os.Executable
+)
+
+// Flags are the paths given on the command line
+type Flags struct {
+	RootDir    string
+	ConfigFile string
+}
+
+// Paths tells where gitlab-shell is installed and where its config file is,
+// and how each of them was found
+type Paths struct {
+	RootDir          string
+	RootDirSource    string
+	ConfigFile       string
+	ConfigFileSource string
+}
+
+// ParseFlags removes the --root-dir and --config flags following the program
+// name from args, and returns them with the other arguments. Both
+// --flag=value and --flag value are accepted. Only leading flags are read, so
+// the arguments of commands are never mistaken for them.
+func ParseFlags(args []string) (*Flags, []string, error) {
+	flags := &Flags{}
+	if len(args) == 0 {
+		return flags, args, nil
+	}
+
+	i := 1
+	for i < len(args) {
+		name, value := args[i], ""
+		hasValue := false
+		if index := strings.Index(name, "="); index != -1 {
+			name, value, hasValue = name[:index], name[index+1:], true
+		}
+
+		var target *string
+		switch name {
+		case RootDirFlag:
+			target = &flags.RootDir
+		case ConfigFileFlag:
+			target = &flags.ConfigFile
+		}
+
+		if target == nil {
+			break
+		}
+
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("Missing value for %s", name)
+			}
+
+			i++
+			value = args[i]
+		}
+
+		if value == "" {
+			return nil, nil, fmt.Errorf("Missing value for %s", name)
+		}
+
+		*target = value
+		i++
+	}
+
+	return flags, append([]string{args[0]}, args[i:]...), nil
+}
+
+// FindPaths looks the root directory up in the --root-dir flag, then the
+// GITLAB_SHELL_DIR environment variable, then DefaultRootDir, and uses
+// heuristic otherwise. The config file is looked up in the --config flag, then
+// GITLAB_SHELL_CONFIG, then DefaultConfigFile, and is config.yml in the root
+// directory otherwise.
+func FindPaths(flags *Flags, heuristic func() (string, error)) (*Paths, error) {
+	paths := &Paths{}
+
+	switch {
+	case flags.RootDir != "":
+		paths.RootDir, paths.RootDirSource = flags.RootDir, RootDirFlag
+	case os.Getenv(RootDirEnv) != "":
+		paths.RootDir, paths.RootDirSource = os.Getenv(RootDirEnv), RootDirEnv
+	case DefaultRootDir != "":
+		paths.RootDir, paths.RootDirSource = DefaultRootDir, compiledInSource
+	default:
+		dir, err := heuristic()
+		if err != nil {
+			return nil, err
+		}
+
+		paths.RootDir, paths.RootDirSource = dir, heuristicSource
+	}
+
+	switch {
+	case flags.ConfigFile != "":
+		paths.ConfigFile, paths.ConfigFileSource = flags.ConfigFile, ConfigFileFlag
+	case os.Getenv(ConfigFileEnv) != "":
+		paths.ConfigFile, paths.ConfigFileSource = os.Getenv(ConfigFileEnv), ConfigFileEnv
+	case DefaultConfigFile != "":
+		paths.ConfigFile, paths.ConfigFileSource = DefaultConfigFile, compiledInSource
+	default:
+		paths.ConfigFile, paths.ConfigFileSource = filepath.Join(paths.RootDir, configFile), rootDirSource
+	}
+
+	// Commands may change their working directory after reading the config
+	var err error
+	if paths.RootDir, err = filepath.Abs(paths.RootDir); err != nil {
+		return nil, err
+	}
+
+	if paths.ConfigFile, err = filepath.Abs(paths.ConfigFile); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// RootDirFromExecutable is the heuristic of binaries installed in the bin
+// directory of gitlab-shell
+func RootDirFromExecutable() (string, error) {
+	path, err := executableFunc()
+	if err != nil {
+		return "", err
+	}
+
+	// Start: /opt/.../gitlab-shell/bin/gitlab-shell
+	// Ends:  /opt/.../gitlab-shell
+	return filepath.Dir(filepath.Dir(path)), nil
+}
+
+// Export sets the environment variables to the paths, so the Ruby
+// implementation run in their place uses the same ones
+func (p *Paths) Export() error {
+	if err := os.Setenv(RootDirEnv, p.RootDir); err != nil {
+		return err
+	}
+
+	return os.Setenv(ConfigFileEnv, p.ConfigFile)
+}
+
+// LogFields returns the paths and their sources for logging
+func (p *Paths) LogFields() map[string]interface{} {
+	return map[string]interface{}{
+		"root_dir":           p.RootDir,
+		"root_dir_source":    p.RootDirSource,
+		"config_file":        p.ConfigFile,
+		"config_file_source": p.ConfigFileSource,
+	}
+}
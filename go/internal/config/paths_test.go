@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/testhelper"
+)
+
+func TestFindPaths(t *testing.T) {
+	heuristic := func() (string, error) {
+		return "/opt/gitlab-shell", nil
+	}
+
+	testCases := []struct {
+		desc              string
+		flags             Flags
+		env               map[string]string
+		defaultRootDir    string
+		defaultConfigFile string
+		expectedPaths     *Paths
+	}{
+		{
+			desc: "Without any configuration",
+			expectedPaths: &Paths{
+				RootDir:          "/opt/gitlab-shell",
+				RootDirSource:    "heuristic",
+				ConfigFile:       "/opt/gitlab-shell/config.yml",
+				ConfigFileSource: "root directory",
+			},
+		},
+		{
+			desc:              "With compiled-in defaults",
+			defaultRootDir:    "/usr/lib/gitlab-shell",
+			defaultConfigFile: "/etc/gitlab-shell/config.yml",
+			expectedPaths: &Paths{
+				RootDir:          "/usr/lib/gitlab-shell",
+				RootDirSource:    "compiled-in default",
+				ConfigFile:       "/etc/gitlab-shell/config.yml",
+				ConfigFileSource: "compiled-in default",
+			},
+		},
+		{
+			desc:              "With environment variables",
+			env:               map[string]string{RootDirEnv: "/srv/gitlab-shell", ConfigFileEnv: "/srv/config.yml"},
+			defaultRootDir:    "/usr/lib/gitlab-shell",
+			defaultConfigFile: "/etc/gitlab-shell/config.yml",
+			expectedPaths: &Paths{
+				RootDir:          "/srv/gitlab-shell",
+				RootDirSource:    "GITLAB_SHELL_DIR",
+				ConfigFile:       "/srv/config.yml",
+				ConfigFileSource: "GITLAB_SHELL_CONFIG",
+			},
+		},
+		{
+			desc:              "With flags",
+			flags:             Flags{RootDir: "/home/git/gitlab-shell", ConfigFile: "/home/git/config.yml"},
+			env:               map[string]string{RootDirEnv: "/srv/gitlab-shell", ConfigFileEnv: "/srv/config.yml"},
+			defaultRootDir:    "/usr/lib/gitlab-shell",
+			defaultConfigFile: "/etc/gitlab-shell/config.yml",
+			expectedPaths: &Paths{
+				RootDir:          "/home/git/gitlab-shell",
+				RootDirSource:    "--root-dir",
+				ConfigFile:       "/home/git/config.yml",
+				ConfigFileSource: "--config",
+			},
+		},
+		{
+			desc:  "With only the root directory flag",
+			flags: Flags{RootDir: "/home/git/gitlab-shell"},
+			env:   map[string]string{ConfigFileEnv: "/srv/config.yml"},
+			expectedPaths: &Paths{
+				RootDir:          "/home/git/gitlab-shell",
+				RootDirSource:    "--root-dir",
+				ConfigFile:       "/srv/config.yml",
+				ConfigFileSource: "GITLAB_SHELL_CONFIG",
+			},
+		},
+		{
+			desc: "With only the root directory",
+			env:  map[string]string{RootDirEnv: "/srv/gitlab-shell"},
+			expectedPaths: &Paths{
+				RootDir:          "/srv/gitlab-shell",
+				RootDirSource:    "GITLAB_SHELL_DIR",
+				ConfigFile:       "/srv/gitlab-shell/config.yml",
+				ConfigFileSource: "root directory",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			env := map[string]string{RootDirEnv: "", ConfigFileEnv: ""}
+			for key, value := range tc.env {
+				env[key] = value
+			}
+			defer testhelper.TempEnv(env)()
+			defer setDefaults(tc.defaultRootDir, tc.defaultConfigFile)()
+
+			paths, err := FindPaths(&tc.flags, heuristic)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedPaths, paths)
+		})
+	}
+}
+
+func TestFindRelativePaths(t *testing.T) {
+	defer testhelper.TempEnv(map[string]string{RootDirEnv: "gitlab-shell", ConfigFileEnv: ""})()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	paths, err := FindPaths(&Flags{}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(wd, "gitlab-shell"), paths.RootDir)
+	require.Equal(t, filepath.Join(wd, "gitlab-shell", "config.yml"), paths.ConfigFile)
+}
+
+func TestFailingFindPaths(t *testing.T) {
+	defer testhelper.TempEnv(map[string]string{RootDirEnv: "", ConfigFileEnv: ""})()
+
+	_, err := FindPaths(&Flags{}, func() (string, error) {
+		return "", errors.New("No executable")
+	})
+
+	require.EqualError(t, err, "No executable")
+}
+
+func TestParseFlags(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		args          []string
+		expectedFlags *Flags
+		expectedArgs  []string
+	}{
+		{
+			desc:          "Without flags",
+			args:          []string{"gitlab-shell", "key-1"},
+			expectedFlags: &Flags{},
+			expectedArgs:  []string{"gitlab-shell", "key-1"},
+		},
+		{
+			desc:          "With flags and values",
+			args:          []string{"gitlab-shell", "--root-dir=/home/git/gitlab-shell", "--config=/home/git/config.yml", "key-1"},
+			expectedFlags: &Flags{RootDir: "/home/git/gitlab-shell", ConfigFile: "/home/git/config.yml"},
+			expectedArgs:  []string{"gitlab-shell", "key-1"},
+		},
+		{
+			desc:          "With values in the next arguments",
+			args:          []string{"gitlab-shell-authorized-keys-sync", "--config", "/home/git/config.yml", "list"},
+			expectedFlags: &Flags{ConfigFile: "/home/git/config.yml"},
+			expectedArgs:  []string{"gitlab-shell-authorized-keys-sync", "list"},
+		},
+		{
+			desc:          "With flags after the arguments",
+			args:          []string{"gitlab-shell-authorized-keys-check", "git", "--config=/tmp/config.yml", "AAAA"},
+			expectedFlags: &Flags{},
+			expectedArgs:  []string{"gitlab-shell-authorized-keys-check", "git", "--config=/tmp/config.yml", "AAAA"},
+		},
+		{
+			desc:          "With other flags",
+			args:          []string{"gitlab-shell-authorized-keys-check", "--config=/home/git/config.yml", "--invalidate-all"},
+			expectedFlags: &Flags{ConfigFile: "/home/git/config.yml"},
+			expectedArgs:  []string{"gitlab-shell-authorized-keys-check", "--invalidate-all"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			flags, args, err := ParseFlags(tc.args)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedFlags, flags)
+			require.Equal(t, tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestFailingParseFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"gitlab-shell", "--config"},
+		{"gitlab-shell", "--root-dir=", "key-1"},
+	} {
+		_, _, err := ParseFlags(args)
+
+		require.Error(t, err)
+	}
+}
+
+func TestRootDirFromExecutable(t *testing.T) {
+	oldExecutable := executableFunc
+	defer func() { executableFunc = oldExecutable }()
+
+	executableFunc = func() (string, error) {
+		return "/opt/gitlab-shell/bin/gitlab-shell", nil
+	}
+
+	dir, err := RootDirFromExecutable()
+
+	require.NoError(t, err)
+	require.Equal(t, "/opt/gitlab-shell", dir)
+}
+
+func TestExport(t *testing.T) {
+	defer testhelper.TempEnv(map[string]string{RootDirEnv: "", ConfigFileEnv: ""})()
+
+	paths := &Paths{RootDir: "/usr/lib/gitlab-shell", ConfigFile: "/etc/gitlab-shell/config.yml"}
+	require.NoError(t, paths.Export())
+
+	require.Equal(t, "/usr/lib/gitlab-shell", os.Getenv(RootDirEnv))
+	require.Equal(t, "/etc/gitlab-shell/config.yml", os.Getenv(ConfigFileEnv))
+}
+
+func TestNewFromPaths(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "gitlab-shell")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	configDir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+
+	configFile := filepath.Join(configDir, "config.yml")
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("log_file: my-log.log\nsecret_file: /dev/null\n"), 0644))
+
+	cfg, err := NewFromPaths(&Paths{RootDir: rootDir, ConfigFile: configFile})
+
+	require.NoError(t, err)
+	require.Equal(t, rootDir, cfg.RootDir)
+	require.Equal(t, filepath.Join(rootDir, "my-log.log"), cfg.LogFile)
+}
+
+func setDefaults(rootDir, configFile string) func() {
+	oldRootDir, oldConfigFile := DefaultRootDir, DefaultConfigFile
+	DefaultRootDir, DefaultConfigFile = rootDir, configFile
+
+	return func() {
+		DefaultRootDir, DefaultConfigFile = oldRootDir, oldConfigFile
+	}
+}
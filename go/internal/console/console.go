@@ -0,0 +1,28 @@
+package console
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// LinePreface matches the prefix gitlab-shell-ruby uses for messages
+	// written to the user, see ConsoleHelper::LINE_PREFACE
+	LinePreface = "> GitLab:"
+)
+
+// DisplayMessages writes every non-empty message on its own line, prefixed
+// so the git client shows where it came from
+func DisplayMessages(out io.Writer, messages []string) {
+	for _, message := range messages {
+		DisplayMessage(out, message)
+	}
+}
+
+func DisplayMessage(out io.Writer, message string) {
+	if message == "" {
+		return
+	}
+
+	fmt.Fprintf(out, "%s %s\n", LinePreface, message)
+}
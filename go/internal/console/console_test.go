@@ -0,0 +1,16 @@
+package console
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayMessages(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	DisplayMessages(out, []string{"first", "", "second"})
+
+	assert.Equal(t, "> GitLab: first\n> GitLab: second\n", out.String())
+}
@@ -0,0 +1,129 @@
+// Package denylist reads the local list of keys, users and networks denied
+// access before gitlab-shell makes any API call
+package denylist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	keyPrefix      = "key-"
+	usernamePrefix = "username-"
+)
+
+// Denylist is parsed from a file with one entry per line, using the same
+// forms sshd passes to gitlab-shell:
+//
+//	# A leaked key
+//	key-123
+//	username-jane-doe
+//	192.0.2.1
+//	198.51.100.0/24
+type Denylist struct {
+	keyIds    map[string]bool
+	usernames map[string]bool
+	networks  []*net.IPNet
+}
+
+// Load reads the denylist at path. A missing file is an empty denylist.
+// Lines that can't be parsed are returned as errors along with the valid
+// entries, so a typo doesn't disable the rest of the list.
+func Load(path string) (*Denylist, []error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Denylist{}, nil
+	}
+	if err != nil {
+		return &Denylist{}, []error{err}
+	}
+	defer file.Close()
+
+	return parse(file)
+}
+
+func parse(reader io.Reader) (*Denylist, []error) {
+	list := &Denylist{keyIds: map[string]bool{}, usernames: map[string]bool{}}
+
+	var errs []error
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := list.add(line); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", lineNumber, err))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return list, errs
+}
+
+func (d *Denylist) add(entry string) error {
+	switch {
+	case strings.HasPrefix(entry, keyPrefix):
+		d.keyIds[strings.TrimPrefix(entry, keyPrefix)] = true
+	case strings.HasPrefix(entry, usernamePrefix):
+		d.usernames[strings.TrimPrefix(entry, usernamePrefix)] = true
+	case strings.Contains(entry, "/"):
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("invalid network %q", entry)
+		}
+		d.networks = append(d.networks, network)
+	default:
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return fmt.Errorf("invalid entry %q", entry)
+		}
+		d.networks = append(d.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+	}
+
+	return nil
+}
+
+// Match returns the entry denying the key, username or remote IP, or an
+// empty string if none does
+func (d *Denylist) Match(keyId, username, remoteIP string) string {
+	if keyId != "" && d.keyIds[keyId] {
+		return keyPrefix + keyId
+	}
+
+	if username != "" && d.usernames[username] {
+		return usernamePrefix + username
+	}
+
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return ""
+	}
+
+	for _, network := range d.networks {
+		if network.Contains(ip) {
+			return network.String()
+		}
+	}
+
+	return ""
+}
+
+// RemoteIP returns the client address from the SSH_CONNECTION value sshd
+// sets, "client_ip client_port server_ip server_port"
+func RemoteIP(sshConnection string) string {
+	fields := strings.Fields(sshConnection)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
@@ -0,0 +1,115 @@
+package denylist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	denylistContent = `
+# Leaked deploy key
+key-123
+username-jane-doe
+192.0.2.1
+198.51.100.0/24
+2001:db8::/32
+`
+)
+
+func TestMatch(t *testing.T) {
+	list, errs := parse(strings.NewReader(denylistContent))
+	require.Empty(t, errs)
+
+	testCases := []struct {
+		desc          string
+		keyId         string
+		username      string
+		remoteIP      string
+		expectedMatch string
+	}{
+		{
+			desc:          "A denied key",
+			keyId:         "123",
+			remoteIP:      "203.0.113.1",
+			expectedMatch: "key-123",
+		},
+		{
+			desc:          "A denied username",
+			username:      "jane-doe",
+			expectedMatch: "username-jane-doe",
+		},
+		{
+			desc:          "A denied IP",
+			keyId:         "1",
+			remoteIP:      "192.0.2.1",
+			expectedMatch: "192.0.2.1/32",
+		},
+		{
+			desc:          "An IP in a denied network",
+			keyId:         "1",
+			remoteIP:      "198.51.100.42",
+			expectedMatch: "198.51.100.0/24",
+		},
+		{
+			desc:          "An IPv6 address in a denied network",
+			keyId:         "1",
+			remoteIP:      "2001:db8::1",
+			expectedMatch: "2001:db8::/32",
+		},
+		{
+			desc:     "An allowed key, user and IP",
+			keyId:    "1234",
+			username: "jane",
+			remoteIP: "192.0.2.2",
+		},
+		{
+			desc:     "An invalid IP",
+			keyId:    "1",
+			remoteIP: "1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expectedMatch, list.Match(tc.keyId, tc.username, tc.remoteIP))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	list, errs := parse(strings.NewReader("key-1\nkey 2\n10.0.0.0/33\n"))
+
+	require.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], `line 2: invalid entry "key 2"`)
+	assert.EqualError(t, errs[1], `line 3: invalid network "10.0.0.0/33"`)
+	assert.Equal(t, "key-1", list.Match("1", "", ""))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "denylist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "denylist")
+
+	list, errs := Load(path)
+	assert.Empty(t, errs)
+	assert.Empty(t, list.Match("123", "jane-doe", "192.0.2.1"))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(denylistContent), 0644))
+
+	list, errs = Load(path)
+	assert.Empty(t, errs)
+	assert.Equal(t, "key-123", list.Match("123", "", ""))
+}
+
+func TestRemoteIP(t *testing.T) {
+	assert.Equal(t, "192.0.2.1", RemoteIP("192.0.2.1 51234 10.0.0.1 22"))
+	assert.Equal(t, "", RemoteIP(""))
+}
@@ -0,0 +1,167 @@
+// Package diskcache keeps small API responses on disk, so short-lived
+// gitlab-shell processes can share them
+package diskcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	tempPrefix = "tmp-"
+)
+
+var (
+	nowFunc = time.Now
+)
+
+// Cache stores entries as files in Dir, named after a hash of their key.
+// Entries are fresh for TTL after they were written. Stale entries are kept
+// so callers can still use them when they can't be refreshed. When there are
+// more than MaxEntries, the oldest are removed, zero means unbounded.
+//
+// Entries are replaced atomically, so concurrent processes can share a cache.
+type Cache struct {
+	Dir        string
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// Get returns the entry for key, and whether it is still fresh. ok is false
+// when there is no entry.
+func (c *Cache) Get(key string) (data []byte, fresh bool, ok bool) {
+	path := c.path(key)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, false
+	}
+
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, false
+	}
+
+	return data, nowFunc().Sub(info.ModTime()) < c.TTL, true
+}
+
+// Set replaces the entry for key. Readers see either the old or the new
+// entry, never a partial one.
+func (c *Cache) Set(key string, data []byte) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(c.Dir, tempPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	now := nowFunc()
+	if err := os.Chtimes(file.Name(), now, now); err != nil {
+		return err
+	}
+
+	if err := os.Rename(file.Name(), c.path(key)); err != nil {
+		return err
+	}
+
+	return c.prune()
+}
+
+// Delete removes the entry for key, if there is one
+func (c *Cache) Delete(key string) error {
+	return c.remove(filepath.Base(c.path(key)))
+}
+
+// Clear removes every entry
+func (c *Cache) Clear() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := c.remove(entry.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prune removes the oldest entries above MaxEntries
+func (c *Cache) prune() error {
+	if c.MaxEntries <= 0 {
+		return nil
+	}
+
+	entries, err := c.entries()
+	if err != nil || len(entries) <= c.MaxEntries {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	for _, entry := range entries[:len(entries)-c.MaxEntries] {
+		if err := c.remove(entry.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entries lists the cache files, skipping files still being written
+func (c *Cache) entries() ([]os.FileInfo, error) {
+	files, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []os.FileInfo
+	for _, file := range files {
+		if file.Mode().IsRegular() && !strings.HasPrefix(file.Name(), tempPrefix) {
+			entries = append(entries, file)
+		}
+	}
+
+	return entries, nil
+}
+
+// remove deletes an entry file, which another process may have removed
+// already
+func (c *Cache) remove(name string) error {
+	if err := os.Remove(filepath.Join(c.Dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
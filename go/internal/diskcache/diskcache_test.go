@@ -0,0 +1,113 @@
+package diskcache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T) (*Cache, func()) {
+	dir, err := ioutil.TempDir("", "diskcache")
+	require.NoError(t, err)
+
+	cache := &Cache{Dir: filepath.Join(dir, "cache"), TTL: time.Minute}
+
+	return cache, func() { os.RemoveAll(dir) }
+}
+
+func TestGetMissing(t *testing.T) {
+	cache, cleanup := setup(t)
+	defer cleanup()
+
+	data, fresh, ok := cache.Get("key")
+
+	assert.Nil(t, data)
+	assert.False(t, fresh)
+	assert.False(t, ok)
+}
+
+func TestSetAndGet(t *testing.T) {
+	cache, cleanup := setup(t)
+	defer cleanup()
+
+	now := time.Now()
+	defer func() { nowFunc = time.Now }()
+	nowFunc = func() time.Time { return now }
+
+	require.NoError(t, cache.Set("key", []byte("value")))
+
+	data, fresh, ok := cache.Get("key")
+	assert.Equal(t, []byte("value"), data)
+	assert.True(t, fresh)
+	assert.True(t, ok)
+
+	_, _, ok = cache.Get("other")
+	assert.False(t, ok)
+
+	nowFunc = func() time.Time { return now.Add(2 * time.Minute) }
+
+	data, fresh, ok = cache.Get("key")
+	assert.Equal(t, []byte("value"), data)
+	assert.False(t, fresh)
+	assert.True(t, ok)
+
+	info, err := os.Stat(cache.Dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestDelete(t *testing.T) {
+	cache, cleanup := setup(t)
+	defer cleanup()
+
+	require.NoError(t, cache.Set("key", []byte("value")))
+	require.NoError(t, cache.Delete("key"))
+	require.NoError(t, cache.Delete("key"))
+
+	_, _, ok := cache.Get("key")
+	assert.False(t, ok)
+}
+
+func TestMaxEntries(t *testing.T) {
+	cache, cleanup := setup(t)
+	defer cleanup()
+	cache.MaxEntries = 2
+
+	now := time.Now()
+	defer func() { nowFunc = time.Now }()
+
+	for i, key := range []string{"first", "second", "third"} {
+		nowFunc = func() time.Time { return now.Add(time.Duration(i) * time.Second) }
+		require.NoError(t, cache.Set(key, []byte(key)))
+	}
+
+	_, _, ok := cache.Get("first")
+	assert.False(t, ok)
+
+	for _, key := range []string{"second", "third"} {
+		data, _, ok := cache.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, []byte(key), data)
+	}
+}
+
+func TestClear(t *testing.T) {
+	cache, cleanup := setup(t)
+	defer cleanup()
+
+	require.NoError(t, cache.Clear())
+
+	require.NoError(t, cache.Set("first", []byte("value")))
+	require.NoError(t, cache.Set("second", []byte("value")))
+	require.NoError(t, cache.Clear())
+
+	for _, key := range []string{"first", "second"} {
+		_, _, ok := cache.Get(key)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,202 @@
+package authorizedkeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/diskcache"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
+)
+
+const (
+	cacheDirName = "authorized_keys"
+
+	listPerPage    = 1000
+	nextPageHeader = "X-Next-Page"
+)
+
+type Client struct {
+	config *config.Config
+	client *gitlabnet.GitlabClient
+	cache  *diskcache.Cache
+}
+
+type Response struct {
+	Id        int64      `json:"id"`
+	Key       string     `json:"key"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func NewClient(config *config.Config) (*Client, error) {
+	client, err := gitlabnet.GetClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating http client: %v", err)
+	}
+
+	return &Client{config: config, client: client, cache: newCache(config)}, nil
+}
+
+func newCache(config *config.Config) *diskcache.Cache {
+	if !config.AuthorizedKeysCache.Enabled {
+		return nil
+	}
+
+	return &diskcache.Cache{
+		Dir:        filepath.Join(config.CacheDir, cacheDirName),
+		TTL:        config.AuthorizedKeysCacheTTL(),
+		MaxEntries: config.AuthorizedKeysCache.MaxEntries,
+	}
+}
+
+// GetByKey looks up a public key. When the cache is enabled, a fresh cached
+// response is used without calling the API, and a stale one when the API
+// fails. Keys the API doesn't know are never cached.
+func (c *Client) GetByKey(key string) (*Response, error) {
+	cached, fresh, ok := c.getCached(key)
+	if ok && fresh {
+		return cached, nil
+	}
+
+	response, err := c.getResponse(key)
+	if err == nil {
+		c.setCached(key, response)
+		return response, nil
+	}
+
+	if !gitlabnet.IsServerError(err) {
+		c.Invalidate(key)
+		return nil, err
+	}
+
+	if ok {
+		logger.Error("Using a stale cached key", err)
+		return cached, nil
+	}
+
+	return nil, err
+}
+
+// Invalidate removes a key from the cache
+func (c *Client) Invalidate(key string) error {
+	if c.cache == nil {
+		return nil
+	}
+
+	return c.cache.Delete(key)
+}
+
+// InvalidateAll removes every key from the cache
+func (c *Client) InvalidateAll() error {
+	if c.cache == nil {
+		return nil
+	}
+
+	return c.cache.Clear()
+}
+
+// ListKeys returns every key GitLab knows, fetching the paginated listing
+// until the API reports there is no next page. A next page that doesn't come
+// after the current one is an error, as following it may never end.
+func (c *Client) ListKeys() ([]*Response, error) {
+	var keys []*Response
+
+	for page := 1; ; {
+		pageKeys, nextPage, err := c.listPage(page)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, pageKeys...)
+
+		if nextPage == "" {
+			break
+		}
+
+		next, err := strconv.Atoi(nextPage)
+		if err != nil || next <= page {
+			return nil, fmt.Errorf("Invalid next page %q after page %d", nextPage, page)
+		}
+
+		page = next
+	}
+
+	return keys, nil
+}
+
+func (c *Client) listPage(page int) ([]*Response, string, error) {
+	params := url.Values{"page": {strconv.Itoa(page)}, "per_page": {strconv.Itoa(listPerPage)}}
+
+	response, err := c.client.Get("/authorized_keys/list?" + params.Encode())
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	var keys []*Response
+	if err := gitlabnet.ParseJSON(response, &keys); err != nil {
+		return nil, "", err
+	}
+
+	return keys, response.Header.Get(nextPageHeader), nil
+}
+
+func (c *Client) getResponse(key string) (*Response, error) {
+	path := "/authorized_keys?" + url.Values{"key": {key}}.Encode()
+
+	response, err := c.client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	return parse(response)
+}
+
+func parse(hr *http.Response) (*Response, error) {
+	response := &Response{}
+	if err := gitlabnet.ParseJSON(hr, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *Client) getCached(key string) (*Response, bool, bool) {
+	if c.cache == nil {
+		return nil, false, false
+	}
+
+	data, fresh, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false, false
+	}
+
+	response := &Response{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, false, false
+	}
+
+	return response, fresh, true
+}
+
+func (c *Client) setCached(key string, response *Response) {
+	if c.cache == nil {
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = c.cache.Set(key, data)
+	}
+
+	if err != nil {
+		logger.Error("Failed to cache the key", err)
+	}
+}
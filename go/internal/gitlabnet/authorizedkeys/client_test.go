@@ -0,0 +1,225 @@
+package authorizedkeys
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/testserver"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeApi struct {
+	requests int
+	status   int
+}
+
+func (f *fakeApi) handler(w http.ResponseWriter, r *http.Request) {
+	f.requests++
+
+	if f.status != http.StatusOK {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	if r.URL.Query().Get("key") == "key+/=" {
+		json.NewEncoder(w).Encode(&Response{Id: 1, Key: "key+/="})
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "404 Not found"})
+	}
+}
+
+func TestGetByKey(t *testing.T) {
+	api := &fakeApi{status: http.StatusOK}
+	client, cleanup := setup(t, api, config.AuthorizedKeysCacheConfig{})
+	defer cleanup()
+
+	result, err := client.GetByKey("key+/=")
+	require.NoError(t, err)
+	assert.Equal(t, &Response{Id: 1, Key: "key+/="}, result)
+
+	_, err = client.GetByKey("unknown")
+	assert.EqualError(t, err, "404 Not found")
+
+	api.status = http.StatusInternalServerError
+	_, err = client.GetByKey("key+/=")
+	assert.EqualError(t, err, "Internal API error (500)")
+}
+
+func TestGetByKeyCached(t *testing.T) {
+	api := &fakeApi{status: http.StatusOK}
+	client, cleanup := setup(t, api, config.AuthorizedKeysCacheConfig{Enabled: true, TTLSeconds: 60})
+	defer cleanup()
+	expected := &Response{Id: 1, Key: "key+/="}
+
+	result, err := client.GetByKey("key+/=")
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	result, err = client.GetByKey("key+/=")
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+	assert.Equal(t, 1, api.requests, "a fresh key is read from the cache")
+
+	// Make the cached key stale
+	client.cache.TTL = 0
+
+	api.status = http.StatusBadGateway
+	result, err = client.GetByKey("key+/=")
+	require.NoError(t, err)
+	assert.Equal(t, expected, result, "a stale key is used when the API fails")
+	assert.Equal(t, 2, api.requests)
+
+	api.status = http.StatusNotFound
+	_, err = client.GetByKey("key+/=")
+	assert.EqualError(t, err, "Internal API error (404)")
+
+	api.status = http.StatusBadGateway
+	_, err = client.GetByKey("key+/=")
+	assert.EqualError(t, err, "Internal API error (502)", "keys the API rejected are removed from the cache")
+}
+
+func TestInvalidate(t *testing.T) {
+	api := &fakeApi{status: http.StatusOK}
+	client, cleanup := setup(t, api, config.AuthorizedKeysCacheConfig{Enabled: true, TTLSeconds: 60})
+	defer cleanup()
+
+	for _, invalidate := range []func() error{
+		func() error { return client.Invalidate("key+/=") },
+		client.InvalidateAll,
+	} {
+		_, err := client.GetByKey("key+/=")
+		require.NoError(t, err)
+
+		require.NoError(t, invalidate())
+
+		requests := api.requests
+		_, err = client.GetByKey("key+/=")
+		require.NoError(t, err)
+		assert.Equal(t, requests+1, api.requests)
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	pages := map[string][]*Response{
+		"1": {{Id: 1, Key: "ssh-rsa AAAA1"}, {Id: 2, Key: "ssh-rsa AAAA2"}},
+		"2": {{Id: 3, Key: "ssh-rsa AAAA3"}},
+	}
+
+	requests := []testserver.TestRequestHandler{
+		{
+			Path: "/api/v4/internal/authorized_keys/list",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				page := r.URL.Query().Get("page")
+				assert.Equal(t, "1000", r.URL.Query().Get("per_page"))
+
+				if page == "1" {
+					w.Header().Set("X-Next-Page", "2")
+				}
+
+				json.NewEncoder(w).Encode(pages[page])
+			},
+		},
+	}
+
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+	defer cleanup()
+
+	client, err := NewClient(&config.Config{GitlabUrl: url})
+	require.NoError(t, err)
+
+	keys, err := client.ListKeys()
+	require.NoError(t, err)
+	assert.Equal(t, append(pages["1"], pages["2"]...), keys)
+}
+
+func TestListKeysWithInvalidNextPage(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		nextPage      string
+		expectedError string
+	}{
+		{
+			desc:          "A repeated page",
+			nextPage:      "1",
+			expectedError: `Invalid next page "1" after page 1`,
+		},
+		{
+			desc:          "A previous page",
+			nextPage:      "0",
+			expectedError: `Invalid next page "0" after page 1`,
+		},
+		{
+			desc:          "A page that isn't a number",
+			nextPage:      "next",
+			expectedError: `Invalid next page "next" after page 1`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			requests := []testserver.TestRequestHandler{
+				{
+					Path: "/api/v4/internal/authorized_keys/list",
+					Handler: func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Set("X-Next-Page", tc.nextPage)
+						json.NewEncoder(w).Encode([]*Response{{Id: 1, Key: "ssh-rsa AAAA1"}})
+					},
+				},
+			}
+
+			cleanup, url, err := testserver.StartSocketHttpServer(requests)
+			require.NoError(t, err)
+			defer cleanup()
+
+			client, err := NewClient(&config.Config{GitlabUrl: url})
+			require.NoError(t, err)
+
+			_, err = client.ListKeys()
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestFailingListKeys(t *testing.T) {
+	api := &fakeApi{status: http.StatusInternalServerError}
+	client, cleanup := setup(t, api, config.AuthorizedKeysCacheConfig{})
+	defer cleanup()
+
+	_, err := client.ListKeys()
+	assert.EqualError(t, err, "Internal API error (500)")
+}
+
+func setup(t *testing.T, api *fakeApi, cacheConfig config.AuthorizedKeysCacheConfig) (*Client, func()) {
+	requests := []testserver.TestRequestHandler{
+		{
+			Path:    "/api/v4/internal/authorized_keys",
+			Handler: api.handler,
+		},
+		{
+			Path:    "/api/v4/internal/authorized_keys/list",
+			Handler: api.handler,
+		},
+	}
+
+	cleanupServer, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "authorizedkeys")
+	require.NoError(t, err)
+
+	client, err := NewClient(&config.Config{GitlabUrl: url, CacheDir: dir, AuthorizedKeysCache: cacheConfig})
+	require.NoError(t, err)
+
+	return client, func() {
+		cleanupServer()
+		os.RemoveAll(dir)
+	}
+}
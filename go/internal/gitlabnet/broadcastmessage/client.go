@@ -0,0 +1,96 @@
+package broadcastmessage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
+)
+
+var (
+	nowFunc = time.Now
+)
+
+type Client struct {
+	config *config.Config
+	client *gitlabnet.GitlabClient
+}
+
+type Message struct {
+	Message  string     `json:"message"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+func NewClient(config *config.Config) (*Client, error) {
+	client, err := gitlabnet.GetClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating http client: %v", err)
+	}
+
+	return &Client{config: config, client: client}, nil
+}
+
+// GetActiveMessages returns the broadcast messages that should be shown now
+func (c *Client) GetActiveMessages() ([]*Message, error) {
+	response, err := c.client.Get("/broadcast_message")
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	messages, err := parse(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []*Message
+	for _, message := range messages {
+		if message.isActive(nowFunc()) {
+			active = append(active, message)
+		}
+	}
+
+	return active, nil
+}
+
+// parse accepts a single message, a list of messages, or an empty object
+// when there is no message
+func parse(hr *http.Response) ([]*Message, error) {
+	body, err := ioutil.ReadAll(hr.Body)
+	if err != nil {
+		return nil, gitlabnet.ParsingError
+	}
+
+	var messages []*Message
+	if err := json.Unmarshal(body, &messages); err == nil {
+		return messages, nil
+	}
+
+	message := &Message{}
+	if err := json.Unmarshal(body, message); err != nil {
+		return nil, gitlabnet.ParsingError
+	}
+
+	return []*Message{message}, nil
+}
+
+func (m *Message) isActive(now time.Time) bool {
+	if m == nil || m.Message == "" {
+		return false
+	}
+
+	if m.StartsAt != nil && now.Before(*m.StartsAt) {
+		return false
+	}
+
+	if m.EndsAt != nil && now.After(*m.EndsAt) {
+		return false
+	}
+
+	return true
+}
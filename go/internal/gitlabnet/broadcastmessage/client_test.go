@@ -0,0 +1,115 @@
+package broadcastmessage
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/testserver"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetActiveMessages(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	defer func() { nowFunc = time.Now }()
+	nowFunc = func() time.Time { return now }
+
+	testCases := []struct {
+		desc             string
+		body             string
+		expectedMessages []string
+	}{
+		{
+			desc: "Without a message",
+			body: "{}",
+		},
+		{
+			desc:             "With a single message",
+			body:             `{"message": "Maintenance tonight", "starts_at": "2019-03-01T00:00:00.000Z", "ends_at": "2019-03-02T00:00:00.000Z"}`,
+			expectedMessages: []string{"Maintenance tonight"},
+		},
+		{
+			desc: "With a list of messages",
+			body: `[
+				{"message": "Active", "starts_at": "2019-03-01T00:00:00.000Z", "ends_at": "2019-03-02T00:00:00.000Z"},
+				{"message": "Expired", "starts_at": "2019-02-01T00:00:00.000Z", "ends_at": "2019-02-02T00:00:00.000Z"},
+				{"message": "Upcoming", "starts_at": "2019-04-01T00:00:00.000Z"},
+				{"message": "No schedule"}
+			]`,
+			expectedMessages: []string{"Active", "No schedule"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			client, cleanup := setup(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tc.body)
+			})
+			defer cleanup()
+
+			messages, err := client.GetActiveMessages()
+			require.NoError(t, err)
+
+			var result []string
+			for _, message := range messages {
+				result = append(result, message.Message)
+			}
+			assert.Equal(t, tc.expectedMessages, result)
+		})
+	}
+}
+
+func TestFailingGetActiveMessages(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		handler       func(w http.ResponseWriter, r *http.Request)
+		expectedError string
+	}{
+		{
+			desc: "When the response is not JSON",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "{ broken")
+			},
+			expectedError: "Parsing failed",
+		},
+		{
+			desc: "When the API fails",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expectedError: "Internal API error (500)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			client, cleanup := setup(t, tc.handler)
+			defer cleanup()
+
+			_, err := client.GetActiveMessages()
+
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func setup(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*Client, func()) {
+	requests := []testserver.TestRequestHandler{
+		{
+			Path:    "/api/v4/internal/broadcast_message",
+			Handler: handler,
+		},
+	}
+
+	cleanup, url, err := testserver.StartSocketHttpServer(requests)
+	require.NoError(t, err)
+
+	client, err := NewClient(&config.Config{GitlabUrl: url})
+	require.NoError(t, err)
+
+	return client, cleanup
+}
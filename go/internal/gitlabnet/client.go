@@ -25,6 +25,32 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// ApiError is returned for error responses of the internal API
+type ApiError struct {
+	StatusCode int
+	Msg        string
+}
+
+func (e *ApiError) Error() string {
+	return e.Msg
+}
+
+// IsServerError tells whether err means the internal API couldn't be reached
+// or failed, rather than rejected the request
+func IsServerError(err error) bool {
+	apiErr, ok := err.(*ApiError)
+
+	return !ok || apiErr.StatusCode >= 500
+}
+
+// IsCustomAction tells whether err is a custom action the internal API asks
+// for instead of handling the request, e.g. proxying it to a Geo primary
+func IsCustomAction(err error) bool {
+	apiErr, ok := err.(*ApiError)
+
+	return ok && apiErr.StatusCode == http.StatusMultipleChoices
+}
+
 type GitlabClient struct {
 	httpClient *http.Client
 	config     *config.Config
@@ -81,9 +107,9 @@ func parseError(resp *http.Response) error {
 	parsedResponse := &ErrorResponse{}
 
 	if err := json.NewDecoder(resp.Body).Decode(parsedResponse); err != nil {
-		return fmt.Errorf("Internal API error (%v)", resp.StatusCode)
+		return &ApiError{StatusCode: resp.StatusCode, Msg: fmt.Sprintf("Internal API error (%v)", resp.StatusCode)}
 	} else {
-		return fmt.Errorf(parsedResponse.Message)
+		return &ApiError{StatusCode: resp.StatusCode, Msg: parsedResponse.Message}
 	}
 
 }
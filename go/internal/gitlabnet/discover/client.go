@@ -1,15 +1,21 @@
 package discover
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
 )
 
+var (
+	errMissingWho = errors.New("who='' is invalid")
+)
+
 type Client struct {
 	config *config.Config
 	client *gitlabnet.GitlabClient
@@ -30,19 +36,52 @@ func NewClient(config *config.Config) (*Client, error) {
 	return &Client{config: config, client: client}, nil
 }
 
+// Identity identifies the user in requests to the internal API, by key id
+// or user id
+type Identity struct {
+	KeyId  string
+	UserId int64
+}
+
 func (c *Client) GetByCommandArgs(args *commandargs.CommandArgs) (*Response, error) {
-	params := url.Values{}
-	if args.GitlabUsername != "" {
-		params.Add("username", args.GitlabUsername)
-	} else if args.GitlabKeyId != "" {
-		params.Add("key_id", args.GitlabKeyId)
-	} else {
+	return c.GetByWho(args.Who)
+}
+
+func (c *Client) GetByWho(who *commandargs.Who) (*Response, error) {
+	if who == nil {
 		// There was no 'who' information, this  matches the ruby error
 		// message.
-		return nil, fmt.Errorf("who='' is invalid")
+		return nil, errMissingWho
 	}
 
-	return c.getResponse(params)
+	return c.getResponse(who.Params())
+}
+
+// GetIdentity returns the key id for keys, and the user id otherwise. The id
+// of usernames is looked up.
+func (c *Client) GetIdentity(who *commandargs.Who) (*Identity, error) {
+	if who == nil {
+		return nil, errMissingWho
+	}
+
+	switch who.Kind {
+	case commandargs.WhoKey:
+		return &Identity{KeyId: who.Value}, nil
+	case commandargs.WhoUser:
+		userId, err := strconv.ParseInt(who.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("who='%s' is invalid!", who)
+		}
+
+		return &Identity{UserId: userId}, nil
+	default:
+		userInfo, err := c.GetByWho(who)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Identity{UserId: userInfo.UserId}, nil
+	}
 }
 
 func (c *Client) getResponse(params url.Values) (*Response, error) {
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"testing"
 
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/testserver"
@@ -78,6 +79,64 @@ func TestGetByUsername(t *testing.T) {
 	assert.Equal(t, &Response{UserId: 1, Username: "jane-doe", Name: "Jane Doe"}, result)
 }
 
+func TestGetByWho(t *testing.T) {
+	client, cleanup := setup(t)
+	defer cleanup()
+
+	result, err := client.GetByWho(&commandargs.Who{Kind: commandargs.WhoUsername, Value: "jane-doe"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Response{UserId: 1, Username: "jane-doe", Name: "Jane Doe"}, result)
+
+	_, err = client.GetByWho(nil)
+	assert.EqualError(t, err, "who='' is invalid")
+}
+
+func TestGetIdentity(t *testing.T) {
+	client, cleanup := setup(t)
+	defer cleanup()
+
+	testCases := []struct {
+		who              *commandargs.Who
+		expectedIdentity *Identity
+	}{
+		{
+			who:              &commandargs.Who{Kind: commandargs.WhoKey, Value: "1"},
+			expectedIdentity: &Identity{KeyId: "1"},
+		},
+		{
+			who:              &commandargs.Who{Kind: commandargs.WhoUser, Value: "3"},
+			expectedIdentity: &Identity{UserId: 3},
+		},
+		{
+			who:              &commandargs.Who{Kind: commandargs.WhoUsername, Value: "jane-doe"},
+			expectedIdentity: &Identity{UserId: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.who.String(), func(t *testing.T) {
+			identity, err := client.GetIdentity(tc.who)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedIdentity, identity)
+		})
+	}
+}
+
+func TestFailingGetIdentity(t *testing.T) {
+	client, cleanup := setup(t)
+	defer cleanup()
+
+	_, err := client.GetIdentity(nil)
+	assert.EqualError(t, err, "who='' is invalid")
+
+	_, err = client.GetIdentity(&commandargs.Who{Kind: commandargs.WhoUser, Value: "jane"})
+	assert.EqualError(t, err, "who='user-jane' is invalid!")
+
+	_, err = client.GetIdentity(&commandargs.Who{Kind: commandargs.WhoUsername, Value: "broken_message"})
+	assert.EqualError(t, err, "Not allowed!")
+}
+
 func TestMissingUser(t *testing.T) {
 	client, cleanup := setup(t)
 	defer cleanup()
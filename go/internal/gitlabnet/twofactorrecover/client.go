@@ -72,18 +72,10 @@ func (c *Client) getRequestBody(args *commandargs.CommandArgs) (*RequestBody, er
 		return nil, err
 	}
 
-	var requestBody *RequestBody
-	if args.GitlabKeyId != "" {
-		requestBody = &RequestBody{KeyId: args.GitlabKeyId}
-	} else {
-		userInfo, err := client.GetByCommandArgs(args)
-
-		if err != nil {
-			return nil, err
-		}
-
-		requestBody = &RequestBody{UserId: userInfo.UserId}
+	identity, err := client.GetIdentity(args.Who)
+	if err != nil {
+		return nil, err
 	}
 
-	return requestBody, nil
+	return &RequestBody{KeyId: identity.KeyId, UserId: identity.UserId}, nil
 }
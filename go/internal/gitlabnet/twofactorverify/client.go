@@ -0,0 +1,85 @@
+package twofactorverify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/commandargs"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/gitlabnet/discover"
+)
+
+type Client struct {
+	config *config.Config
+	client *gitlabnet.GitlabClient
+}
+
+type Response struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+type RequestBody struct {
+	KeyId      string `json:"key_id,omitempty"`
+	UserId     int64  `json:"user_id,omitempty"`
+	OTPAttempt string `json:"otp_attempt"`
+}
+
+func NewClient(config *config.Config) (*Client, error) {
+	client, err := gitlabnet.GetClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating http client: %v", err)
+	}
+
+	return &Client{config: config, client: client}, nil
+}
+
+// VerifyOTP returns nil if GitLab accepted the one-time password, and an
+// error explaining why not otherwise
+func (c *Client) VerifyOTP(args *commandargs.CommandArgs, otp string) error {
+	requestBody, err := c.getRequestBody(args, otp)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.client.Post("/two_factor_otp_check", requestBody)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return parse(response)
+}
+
+func parse(hr *http.Response) error {
+	response := &Response{}
+	if err := gitlabnet.ParseJSON(hr, response); err != nil {
+		return err
+	}
+
+	if !response.Success {
+		return errors.New(response.Message)
+	}
+
+	return nil
+}
+
+func (c *Client) getRequestBody(args *commandargs.CommandArgs, otp string) (*RequestBody, error) {
+	client, err := discover.NewClient(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	requestBody := &RequestBody{OTPAttempt: otp}
+	identity, err := client.GetIdentity(args.Who)
+	if err != nil {
+		return nil, err
+	}
+
+	requestBody.KeyId = identity.KeyId
+	requestBody.UserId = identity.UserId
+
+	return requestBody, nil
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/limiter"
+)
+
+const (
+	concurrencyExitCode = 8
+)
+
+type concurrencyLimit struct {
+	kind    string
+	value   string
+	limit   int
+	message string
+}
+
+// acquireConcurrencySlots takes a slot for the user and the repository of the
+// request, so a single user or script can't exhaust a Gitaly node. The
+// returned function releases the slots.
+func acquireConcurrencySlots(ctx context.Context, cfg *config.Config, request *gitalyRequest) (func(), error) {
+	sessionLimiter := &limiter.Limiter{Dir: cfg.Concurrency.LockDir}
+
+	var releases []func()
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+
+	limits := []concurrencyLimit{
+		{
+			kind:    "user",
+			value:   request.GlId,
+			limit:   cfg.Concurrency.PerUser,
+			message: "You are running too many git operations at the same time, please try again later.",
+		},
+		{
+			kind:    "repository",
+			value:   request.GlRepository,
+			limit:   cfg.Concurrency.PerRepository,
+			message: "Too many git operations are running on this repository, please try again later.",
+		},
+	}
+
+	for _, l := range limits {
+		if l.limit <= 0 || l.value == "" {
+			continue
+		}
+
+		release, err := sessionLimiter.Acquire(ctx, l.kind+":"+l.value, l.limit, cfg.QueueTimeout())
+		if err == limiter.ErrLimitExceeded {
+			releaseAll()
+			return nil, &userError{
+				message:  l.message,
+				exitCode: concurrencyExitCode,
+				cause:    fmt.Errorf("%v for %s %s (limit %d)", err, l.kind, l.value, l.limit),
+			}
+		}
+
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+
+		releases = append(releases, release)
+	}
+
+	return releaseAll, nil
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+func TestAcquireConcurrencySlots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitlab-shell-concurrency")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := &config.Config{
+		Concurrency: config.ConcurrencyLimitsConfig{LockDir: dir, PerUser: 1, PerRepository: 2},
+	}
+
+	request := func(glId, glRepository string) *gitalyRequest {
+		return &gitalyRequest{GlId: glId, GlRepository: glRepository}
+	}
+
+	release, err := acquireConcurrencySlots(context.Background(), cfg, request("key-1", "project-1"))
+	require.NoError(t, err)
+	defer release()
+
+	_, err = acquireConcurrencySlots(context.Background(), cfg, request("key-1", "project-2"))
+	userErr, ok := translateError(err)
+	require.True(t, ok)
+	require.Equal(t, concurrencyExitCode, userErr.exitCode)
+	require.Equal(t, "You are running too many git operations at the same time, please try again later.", userErr.message)
+	require.EqualError(t, err, "Concurrency limit exceeded for user key-1 (limit 1)")
+
+	release, err = acquireConcurrencySlots(context.Background(), cfg, request("key-2", "project-1"))
+	require.NoError(t, err)
+	defer release()
+
+	_, err = acquireConcurrencySlots(context.Background(), cfg, request("key-3", "project-1"))
+	require.EqualError(t, err, "Concurrency limit exceeded for repository project-1 (limit 2)")
+
+	// The user slot taken before the repository limit was hit is released
+	release, err = acquireConcurrencySlots(context.Background(), cfg, request("key-3", "project-3"))
+	require.NoError(t, err)
+	release()
+}
+
+func TestAcquireConcurrencySlotsUnlimited(t *testing.T) {
+	cfg := &config.Config{}
+
+	release, err := acquireConcurrencySlots(context.Background(), cfg, &gitalyRequest{GlId: "key-1"})
+	require.NoError(t, err)
+	release()
+}
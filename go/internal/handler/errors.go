@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// userError is what we show the user when a Gitaly call fails. The messages
+// are deliberately generic: the underlying error can contain details that
+// are private to the GitLab server, so it is only written to the log.
+type userError struct {
+	message  string
+	exitCode int
+	cause    error
+}
+
+func (e *userError) Error() string {
+	if e.cause != nil {
+		return e.cause.Error()
+	}
+
+	return e.message
+}
+
+var (
+	userErrors = map[codes.Code]userError{
+		codes.Unavailable: {
+			message:  "The git server is currently unavailable, please try again later.",
+			exitCode: 3,
+		},
+		codes.DeadlineExceeded: {
+			message:  "The git operation took too long and was stopped, please try again later.",
+			exitCode: 4,
+		},
+		codes.NotFound: {
+			message:  "The repository could not be found.",
+			exitCode: 5,
+		},
+		codes.PermissionDenied: {
+			message:  "You are not allowed to perform this git operation.",
+			exitCode: 6,
+		},
+		codes.ResourceExhausted: {
+			message:  "The git server is too busy to handle your request, please try again later.",
+			exitCode: 7,
+		},
+	}
+)
+
+// translateError returns the message and exit code to use for a Gitaly
+// error, if it is one we know how to explain to the user
+func translateError(err error) (*userError, bool) {
+	if userErr, ok := err.(*userError); ok {
+		return userErr, true
+	}
+
+	grpcStatus, ok := status.FromError(err)
+	if !ok || grpcStatus == nil {
+		return nil, false
+	}
+
+	userErr, ok := userErrors[grpcStatus.Code()]
+	if !ok {
+		return nil, false
+	}
+
+	return &userErr, true
+}
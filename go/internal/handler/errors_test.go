@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTranslateError(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		err              error
+		expectedOk       bool
+		expectedExitCode int
+	}{
+		{
+			desc:             "Unavailable",
+			err:              status.Error(codes.Unavailable, "connection refused"),
+			expectedOk:       true,
+			expectedExitCode: 3,
+		},
+		{
+			desc:             "DeadlineExceeded",
+			err:              status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+			expectedOk:       true,
+			expectedExitCode: 4,
+		},
+		{
+			desc:             "NotFound",
+			err:              status.Error(codes.NotFound, "GetRepoPath: not a git repository"),
+			expectedOk:       true,
+			expectedExitCode: 5,
+		},
+		{
+			desc:             "PermissionDenied",
+			err:              status.Error(codes.PermissionDenied, "authentication required"),
+			expectedOk:       true,
+			expectedExitCode: 6,
+		},
+		{
+			desc:             "ResourceExhausted",
+			err:              status.Error(codes.ResourceExhausted, "maximum queue size reached"),
+			expectedOk:       true,
+			expectedExitCode: 7,
+		},
+		{
+			desc:       "An unmapped gRPC code",
+			err:        status.Error(codes.Internal, "something broke"),
+			expectedOk: false,
+		},
+		{
+			desc:       "A non-gRPC error",
+			err:        fmt.Errorf("invalid request"),
+			expectedOk: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			userErr, ok := translateError(tc.err)
+
+			require.Equal(t, tc.expectedOk, ok)
+			if tc.expectedOk {
+				require.Equal(t, tc.expectedExitCode, userErr.exitCode)
+				require.NotContains(t, userErr.message, tc.err.Error())
+			}
+		})
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"gitlab.com/gitlab-org/gitaly/auth"
 	"gitlab.com/gitlab-org/gitaly/client"
 
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/console"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/logger"
 	"gitlab.com/gitlab-org/labkit/tracing"
 	"google.golang.org/grpc"
@@ -16,20 +20,27 @@ import (
 
 // GitalyHandlerFunc implementations are responsible for deserializing
 // the request JSON into a GRPC request message, making an appropriate Gitaly
-// call with the request, using the provided client, and returning the exit code
-// or error from the Gitaly call.
-type GitalyHandlerFunc func(ctx context.Context, client *grpc.ClientConn, requestJSON string) (int32, error)
+// call with the request, using the provided client and streams, and returning
+// the exit code or error from the Gitaly call.
+type GitalyHandlerFunc func(ctx context.Context, client *grpc.ClientConn, readWriter *readwriter.ReadWriter, requestJSON string) (int32, error)
 
 // RunGitalyCommand provides a bootstrap for Gitaly commands executed
 // through GitLab-Shell. It ensures that logging, tracing and other
 // common concerns are configured before executing the `handler`.
 // RunGitalyCommand will handle errors internally and call
-// `os.Exit()` on completion. This method will never return to
-// the caller.
+// `os.Exit()` on completion. Gitaly errors the user can act on are
+// explained on stderr and get a distinct exit code. This method will
+// never return to the caller.
 func RunGitalyCommand(handler GitalyHandlerFunc) {
 	exitCode, err := internalRunGitalyCommand(os.Args, handler)
 
 	if err != nil {
+		if userErr, ok := translateError(err); ok {
+			logger.Error("error: %v", err)
+			console.DisplayMessage(os.Stderr, userErr.message)
+			os.Exit(userErr.exitCode)
+		}
+
 		logger.Fatal("error: %v", err)
 	}
 
@@ -39,12 +50,33 @@ func RunGitalyCommand(handler GitalyHandlerFunc) {
 // internalRunGitalyCommand is like RunGitalyCommand, except that since it doesn't
 // call os.Exit, we can rely on its deferred handlers executing correctly
 func internalRunGitalyCommand(args []string, handler GitalyHandlerFunc) (int, error) {
+	flags, args, err := config.ParseFlags(args)
+	if err != nil {
+		return 1, err
+	}
 
 	if len(args) != 3 {
 		return 1, fmt.Errorf("expected 2 arguments, got %v", args)
 	}
 
-	cfg, err := config.New()
+	// Read the request before changing directory, as a request file may be
+	// given relative to the working directory
+	requestJSON, err := readRequest(args[2])
+	if err != nil {
+		return 1, err
+	}
+
+	request, err := parseRequest(requestJSON)
+	if err != nil {
+		return 1, err
+	}
+
+	paths, err := config.FindPaths(flags, config.RootDirFromExecutable)
+	if err != nil {
+		return 1, err
+	}
+
+	cfg, err := config.NewFromPaths(paths)
 	if err != nil {
 		return 1, err
 	}
@@ -52,6 +84,7 @@ func internalRunGitalyCommand(args []string, handler GitalyHandlerFunc) (int, er
 	if err := logger.Configure(cfg); err != nil {
 		return 1, err
 	}
+	logger.Info("Found the gitlab-shell paths", paths.LogFields())
 
 	// Use a working directory that won't get removed or unmounted.
 	if err := os.Chdir("/"); err != nil {
@@ -78,6 +111,12 @@ func internalRunGitalyCommand(args []string, handler GitalyHandlerFunc) (int, er
 	ctx, finished := tracing.ExtractFromEnv(context.Background())
 	defer finished()
 
+	release, err := acquireConcurrencySlots(ctx, cfg, request)
+	if err != nil {
+		return 1, err
+	}
+	defer release()
+
 	gitalyAddress := args[1]
 	if gitalyAddress == "" {
 		return 1, fmt.Errorf("no gitaly_address given")
@@ -89,11 +128,34 @@ func internalRunGitalyCommand(args []string, handler GitalyHandlerFunc) (int, er
 	}
 	defer conn.Close()
 
-	requestJSON := string(args[2])
-	exitCode, err := handler(ctx, conn, requestJSON)
+	gitCommand := gitCommandName(args[0])
+	ctx, session := startSession(ctx, cfg.IdleTimeout(), cfg.MaxDuration(gitCommand))
+	defer session.close()
+
+	rateLimit := request.rateLimit(cfg.RateLimit(gitCommand))
+	readWriter := &readwriter.ReadWriter{
+		In:     throttleReader(session.reader(os.Stdin), rateLimit.UploadBytesPerSecond),
+		Out:    throttleWriter(session.writer(os.Stdout), rateLimit.DownloadBytesPerSecond),
+		ErrOut: session.writer(os.Stderr),
+	}
+
+	exitCode, err := handler(ctx, conn, readWriter, requestJSON)
+	if err != nil {
+		// Report why we cancelled the session rather than the cancellation
+		if sessionErr := session.expired(); sessionErr != nil {
+			return 1, sessionErr
+		}
+	}
+
 	return int(exitCode), err
 }
 
+// gitCommandName returns the git command a gitaly executable proxies,
+// e.g. git-upload-pack for /path/to/bin/gitaly-upload-pack
+func gitCommandName(executable string) string {
+	return "git-" + strings.TrimPrefix(filepath.Base(executable), "gitaly-")
+}
+
 func dialOpts() []grpc.DialOption {
 	connOpts := client.DefaultDialOpts
 	if token := os.Getenv("GITALY_TOKEN"); token != "" {
@@ -3,25 +3,39 @@ package handler
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
 	"gitlab.com/gitlab-org/gitlab-shell/go/internal/testhelper"
 	"google.golang.org/grpc"
 )
 
+const (
+	validRequest = `{"repository":{"storage_name":"default","relative_path":"group/project.git"}}`
+)
+
+var (
+	// The test binary isn't in the bin directory of the test root
+	rootDirEnv = map[string]string{config.RootDirEnv: testhelper.TestRoot, config.ConfigFileEnv: ""}
+)
+
 func TestInteralRunHandler(t *testing.T) {
 	type testCase struct {
 		name    string
 		args    []string
-		handler func(context.Context, *grpc.ClientConn, string) (int32, error)
+		handler func(context.Context, *grpc.ClientConn, *readwriter.ReadWriter, string) (int32, error)
 		want    int
 		wantErr bool
 	}
 
 	var currentTest *testCase
-	makeHandler := func(r1 int32, r2 error) func(context.Context, *grpc.ClientConn, string) (int32, error) {
-		return func(ctx context.Context, client *grpc.ClientConn, requestJSON string) (int32, error) {
+	makeHandler := func(r1 int32, r2 error) func(context.Context, *grpc.ClientConn, *readwriter.ReadWriter, string) (int32, error) {
+		return func(ctx context.Context, client *grpc.ClientConn, readWriter *readwriter.ReadWriter, requestJSON string) (int32, error) {
 			require.NotNil(t, ctx)
 			require.NotNil(t, client)
 			require.Equal(t, currentTest.args[2], requestJSON)
@@ -31,28 +45,28 @@ func TestInteralRunHandler(t *testing.T) {
 	tests := []testCase{
 		{
 			name:    "expected",
-			args:    []string{"test", "tcp://localhost:9999", "{}"},
+			args:    []string{"test", "tcp://localhost:9999", validRequest},
 			handler: makeHandler(0, nil),
 			want:    0,
 			wantErr: false,
 		},
 		{
 			name:    "handler_error",
-			args:    []string{"test", "tcp://localhost:9999", "{}"},
+			args:    []string{"test", "tcp://localhost:9999", validRequest},
 			handler: makeHandler(0, fmt.Errorf("error")),
 			want:    0,
 			wantErr: true,
 		},
 		{
 			name:    "handler_exitcode",
-			args:    []string{"test", "tcp://localhost:9999", "{}"},
+			args:    []string{"test", "tcp://localhost:9999", validRequest},
 			handler: makeHandler(1, nil),
 			want:    1,
 			wantErr: false,
 		},
 		{
 			name:    "handler_error_exitcode",
-			args:    []string{"test", "tcp://localhost:9999", "{}"},
+			args:    []string{"test", "tcp://localhost:9999", validRequest},
 			handler: makeHandler(1, fmt.Errorf("error")),
 			want:    1,
 			wantErr: true,
@@ -71,9 +85,16 @@ func TestInteralRunHandler(t *testing.T) {
 			want:    1,
 			wantErr: true,
 		},
+		{
+			name:    "invalid_request",
+			args:    []string{"test", "tcp://localhost:9999", "{}"},
+			handler: makeHandler(10, nil),
+			want:    1,
+			wantErr: true,
+		},
 		{
 			name:    "empty_gitaly_address",
-			args:    []string{"test", "", "{}"},
+			args:    []string{"test", "", validRequest},
 			handler: makeHandler(10, nil),
 			want:    1,
 			wantErr: true,
@@ -89,6 +110,7 @@ func TestInteralRunHandler(t *testing.T) {
 			done, err := testhelper.PrepareTestRootDir()
 			defer done()
 			require.NoError(t, err)
+			defer testhelper.TempEnv(rootDirEnv)()
 
 			got, err := internalRunGitalyCommand(tt.args, tt.handler)
 			if tt.wantErr {
@@ -101,3 +123,68 @@ func TestInteralRunHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestInteralRunHandlerRequestFile(t *testing.T) {
+	done, err := testhelper.PrepareTestRootDir()
+	defer done()
+	require.NoError(t, err)
+	defer testhelper.TempEnv(rootDirEnv)()
+
+	requestFile := filepath.Join(testhelper.TestRoot, "request.json")
+	require.NoError(t, ioutil.WriteFile(requestFile, []byte(validRequest), 0600))
+
+	handler := func(ctx context.Context, client *grpc.ClientConn, readWriter *readwriter.ReadWriter, requestJSON string) (int32, error) {
+		require.Equal(t, validRequest, requestJSON)
+		return 0, nil
+	}
+
+	got, err := internalRunGitalyCommand([]string{"test", "tcp://localhost:9999", "--request-file=" + requestFile}, handler)
+	require.NoError(t, err)
+	require.Equal(t, 0, got)
+
+	_, err = os.Stat(requestFile)
+	require.True(t, os.IsNotExist(err), "expected the request file to be removed")
+}
+
+func TestInteralRunHandlerRequestFd(t *testing.T) {
+	done, err := testhelper.PrepareTestRootDir()
+	defer done()
+	require.NoError(t, err)
+	defer testhelper.TempEnv(rootDirEnv)()
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(validRequest))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	handler := func(ctx context.Context, client *grpc.ClientConn, readWriter *readwriter.ReadWriter, requestJSON string) (int32, error) {
+		require.Equal(t, validRequest, requestJSON)
+		return 0, nil
+	}
+
+	got, err := internalRunGitalyCommand([]string{"test", "tcp://localhost:9999", fmt.Sprintf("--request-fd=%d", reader.Fd())}, handler)
+	require.NoError(t, err)
+	require.Equal(t, 0, got)
+}
+
+func TestInteralRunHandlerWithRootDirFlag(t *testing.T) {
+	done, err := testhelper.PrepareTestRootDir()
+	defer done()
+	require.NoError(t, err)
+	defer testhelper.TempEnv(map[string]string{config.RootDirEnv: "/does/not/exist", config.ConfigFileEnv: ""})()
+
+	handler := func(ctx context.Context, client *grpc.ClientConn, readWriter *readwriter.ReadWriter, requestJSON string) (int32, error) {
+		require.Equal(t, validRequest, requestJSON)
+		return 0, nil
+	}
+
+	got, err := internalRunGitalyCommand([]string{"test", "--root-dir=" + testhelper.TestRoot, "tcp://localhost:9999", validRequest}, handler)
+	require.NoError(t, err)
+	require.Equal(t, 0, got)
+}
+
+func TestGitCommandName(t *testing.T) {
+	require.Equal(t, "git-upload-pack", gitCommandName("/opt/gitlab-shell/bin/gitaly-upload-pack"))
+	require.Equal(t, "git-receive-pack", gitCommandName("gitaly-receive-pack"))
+}
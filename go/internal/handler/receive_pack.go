@@ -2,17 +2,17 @@ package handler
 
 import (
 	"context"
-	"os"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go/gitalypb"
 	"gitlab.com/gitlab-org/gitaly/client"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/command/readwriter"
 	"google.golang.org/grpc"
 )
 
 // ReceivePack issues a Gitaly receive-pack rpc to the provided address
-func ReceivePack(ctx context.Context, conn *grpc.ClientConn, request *pb.SSHReceivePackRequest) (int32, error) {
+func ReceivePack(ctx context.Context, conn *grpc.ClientConn, readWriter *readwriter.ReadWriter, request *pb.SSHReceivePackRequest) (int32, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return client.ReceivePack(ctx, conn, os.Stdin, os.Stdout, os.Stderr, request)
+	return client.ReceivePack(ctx, conn, readWriter.In, readWriter.Out, readWriter.ErrOut, request)
 }
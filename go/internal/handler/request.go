@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+const (
+	requestFdPrefix   = "--request-fd="
+	requestFilePrefix = "--request-file="
+
+	// maxRequestSize bounds how much we're willing to read from a request
+	// file descriptor or file. Requests are a few kilobytes at most.
+	maxRequestSize = 1 << 20
+)
+
+// gitalyRequest holds the parts of the request JSON gitlab-shell itself
+// cares about. The full request is deserialized by the GitalyHandlerFunc.
+type gitalyRequest struct {
+	Repository *struct {
+		StorageName  string `json:"storage_name"`
+		RelativePath string `json:"relative_path"`
+	} `json:"repository"`
+	GlId         string `json:"gl_id"`
+	GlRepository string `json:"gl_repository"`
+
+	// RateLimit is returned by /allowed to override the configured limits
+	RateLimit *struct {
+		UploadBytesPerSecond   *uint64 `json:"upload_bytes_per_second"`
+		DownloadBytesPerSecond *uint64 `json:"download_bytes_per_second"`
+	} `json:"rate_limit"`
+}
+
+// readRequest returns the request JSON described by argument. The request
+// contains user and repository details, and argv is readable by anyone on the
+// host through `ps`, so callers should pass it as `--request-fd=N` (an
+// inherited file descriptor) or `--request-file=PATH` (a file only its owner
+// can access, removed once read). For backward compatibility any other
+// argument is treated as the request JSON itself.
+func readRequest(argument string) (string, error) {
+	if strings.HasPrefix(argument, requestFdPrefix) {
+		return readRequestFd(strings.TrimPrefix(argument, requestFdPrefix))
+	}
+
+	if strings.HasPrefix(argument, requestFilePrefix) {
+		return readRequestFile(strings.TrimPrefix(argument, requestFilePrefix))
+	}
+
+	return argument, nil
+}
+
+func readRequestFd(value string) (string, error) {
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid request file descriptor %q", value)
+	}
+
+	// Standard input and output carry the git protocol data
+	if fd <= 2 {
+		return "", fmt.Errorf("request file descriptor must be greater than 2, got %d", fd)
+	}
+
+	file := os.NewFile(uintptr(fd), "request")
+	if file == nil {
+		return "", fmt.Errorf("invalid request file descriptor %d", fd)
+	}
+	defer file.Close()
+
+	return readLimited(file)
+}
+
+func readRequestFile(path string) (string, error) {
+	// The checks are made on the opened file, so it can't be swapped for
+	// another one after them. Symlinks aren't followed, and opening a FIFO
+	// doesn't wait for a writer.
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("request file %s is not a regular file", path)
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("request file %s must not be accessible by group or others, has mode %04o", path, info.Mode().Perm())
+	}
+
+	// The request is only meant to be read once
+	if err := os.Remove(path); err != nil {
+		return "", err
+	}
+
+	return readLimited(file)
+}
+
+func readLimited(reader io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxRequestSize+1))
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) > maxRequestSize {
+		return "", fmt.Errorf("request exceeds %d bytes", maxRequestSize)
+	}
+
+	return string(data), nil
+}
+
+// parseRequest checks the fields every Gitaly SSH request needs, so a
+// malformed request is reported clearly instead of failing inside Gitaly.
+func parseRequest(requestJSON string) (*gitalyRequest, error) {
+	var request gitalyRequest
+	if err := json.Unmarshal([]byte(requestJSON), &request); err != nil {
+		return nil, fmt.Errorf("invalid request JSON: %v", err)
+	}
+
+	if request.Repository == nil {
+		return nil, fmt.Errorf("invalid request: missing repository")
+	}
+
+	var missing []string
+	if request.Repository.StorageName == "" {
+		missing = append(missing, "repository.storage_name")
+	}
+
+	if request.Repository.RelativePath == "" {
+		missing = append(missing, "repository.relative_path")
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("invalid request: missing %s", strings.Join(missing, ", "))
+	}
+
+	return &request, nil
+}
+
+// rateLimit returns the configured rate limit, overridden by any limits
+// included in the request
+func (r *gitalyRequest) rateLimit(limit config.RateLimitConfig) config.RateLimitConfig {
+	if r.RateLimit == nil {
+		return limit
+	}
+
+	if r.RateLimit.UploadBytesPerSecond != nil {
+		limit.UploadBytesPerSecond = *r.RateLimit.UploadBytesPerSecond
+	}
+
+	if r.RateLimit.DownloadBytesPerSecond != nil {
+		limit.DownloadBytesPerSecond = *r.RateLimit.DownloadBytesPerSecond
+	}
+
+	return limit
+}
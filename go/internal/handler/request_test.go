@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+func TestReadRequest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitlab-shell-request")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	worldReadable := filepath.Join(dir, "world-readable.json")
+	require.NoError(t, ioutil.WriteFile(worldReadable, []byte(validRequest), 0644))
+
+	private := filepath.Join(dir, "private.json")
+	require.NoError(t, ioutil.WriteFile(private, []byte(validRequest), 0600))
+
+	symlink := filepath.Join(dir, "symlink.json")
+	require.NoError(t, os.Symlink(private, symlink))
+
+	fifo := filepath.Join(dir, "fifo.json")
+	require.NoError(t, syscall.Mkfifo(fifo, 0600))
+
+	testCases := []struct {
+		desc          string
+		argument      string
+		expected      string
+		expectedError string
+	}{
+		{
+			desc:     "JSON passed as an argument",
+			argument: validRequest,
+			expected: validRequest,
+		},
+		{
+			desc:          "A non-numeric file descriptor",
+			argument:      "--request-fd=foo",
+			expectedError: `invalid request file descriptor "foo"`,
+		},
+		{
+			desc:          "Standard input as file descriptor",
+			argument:      "--request-fd=0",
+			expectedError: "request file descriptor must be greater than 2, got 0",
+		},
+		{
+			desc:          "A missing request file",
+			argument:      "--request-file=" + filepath.Join(dir, "missing.json"),
+			expectedError: "open " + filepath.Join(dir, "missing.json") + ": no such file or directory",
+		},
+		{
+			desc:          "A request file readable by others",
+			argument:      "--request-file=" + worldReadable,
+			expectedError: "request file " + worldReadable + " must not be accessible by group or others, has mode 0644",
+		},
+		{
+			desc:          "A request file that is a directory",
+			argument:      "--request-file=" + dir,
+			expectedError: "request file " + dir + " is not a regular file",
+		},
+		{
+			desc:          "A symlink to a request file",
+			argument:      "--request-file=" + symlink,
+			expectedError: "open " + symlink + ": too many levels of symbolic links",
+		},
+		{
+			desc:          "A request file that is a FIFO",
+			argument:      "--request-file=" + fifo,
+			expectedError: "request file " + fifo + " is not a regular file",
+		},
+		{
+			desc:     "A private request file",
+			argument: "--request-file=" + private,
+			expected: validRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := readRequest(tc.argument)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, result)
+			}
+		})
+	}
+
+	_, err = os.Stat(private)
+	require.True(t, os.IsNotExist(err), "the request file is removed once read")
+}
+
+func TestParseRequest(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		requestJSON   string
+		expectedError string
+	}{
+		{
+			desc:        "A valid request",
+			requestJSON: validRequest,
+		},
+		{
+			desc:          "Invalid JSON",
+			requestJSON:   `{"repository": `,
+			expectedError: "invalid request JSON: unexpected end of JSON input",
+		},
+		{
+			desc:          "A missing repository",
+			requestJSON:   `{"gl_id": "key-1"}`,
+			expectedError: "invalid request: missing repository",
+		},
+		{
+			desc:          "A repository without storage name",
+			requestJSON:   `{"repository": {"relative_path": "group/project.git"}}`,
+			expectedError: "invalid request: missing repository.storage_name",
+		},
+		{
+			desc:          "An empty repository",
+			requestJSON:   `{"repository": {}}`,
+			expectedError: "invalid request: missing repository.storage_name, repository.relative_path",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := parseRequest(tc.requestJSON)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRequestRateLimit(t *testing.T) {
+	configured := config.RateLimitConfig{UploadBytesPerSecond: 100, DownloadBytesPerSecond: 200}
+
+	testCases := []struct {
+		desc        string
+		requestJSON string
+		expected    config.RateLimitConfig
+	}{
+		{
+			desc:        "Without a rate limit in the request",
+			requestJSON: validRequest,
+			expected:    configured,
+		},
+		{
+			desc:        "With a partial rate limit in the request",
+			requestJSON: `{"repository":{"storage_name":"default","relative_path":"project.git"},"rate_limit":{"download_bytes_per_second":50}}`,
+			expected:    config.RateLimitConfig{UploadBytesPerSecond: 100, DownloadBytesPerSecond: 50},
+		},
+		{
+			desc:        "With a rate limit lifting the configured limits",
+			requestJSON: `{"repository":{"storage_name":"default","relative_path":"project.git"},"rate_limit":{"upload_bytes_per_second":0,"download_bytes_per_second":0}}`,
+			expected:    config.RateLimitConfig{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			request, err := parseRequest(tc.requestJSON)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expected, request.rateLimit(configured))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// session enforces the idle and maximum duration timeouts of a git
+// operation. Clients that stop sending or receiving data would otherwise keep
+// their Gitaly RPC open indefinitely.
+type session struct {
+	idleTimeout  time.Duration
+	lastActivity int64 // Unix nanoseconds, only accessed atomically
+	cancel       context.CancelFunc
+	maxTimer     *time.Timer
+	done         chan struct{}
+	closeOnce    sync.Once
+	mutex        sync.Mutex
+	err          error
+}
+
+// startSession returns a context that is cancelled once the session was idle
+// for longer than idleTimeout, or ran for longer than maxDuration. A zero
+// duration disables that timeout. The caller must call close() when done.
+func startSession(ctx context.Context, idleTimeout, maxDuration time.Duration) (context.Context, *session) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s := &session{
+		idleTimeout: idleTimeout,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	s.touch()
+
+	if maxDuration > 0 {
+		s.maxTimer = time.AfterFunc(maxDuration, func() {
+			s.expire(status.Errorf(codes.DeadlineExceeded, "session exceeded the maximum duration of %v", maxDuration))
+		})
+	}
+
+	if idleTimeout > 0 {
+		go s.watchIdle()
+	}
+
+	return ctx, s
+}
+
+func (s *session) watchIdle() {
+	timer := time.NewTimer(s.idleTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-timer.C:
+			idle := time.Since(time.Unix(0, atomic.LoadInt64(&s.lastActivity)))
+			if idle >= s.idleTimeout {
+				s.expire(status.Errorf(codes.DeadlineExceeded, "session was idle for more than %v", s.idleTimeout))
+				return
+			}
+
+			timer.Reset(s.idleTimeout - idle)
+		}
+	}
+}
+
+func (s *session) touch() {
+	atomic.StoreInt64(&s.lastActivity, time.Now().UnixNano())
+}
+
+func (s *session) expire(err error) {
+	s.mutex.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mutex.Unlock()
+
+	s.cancel()
+}
+
+// expired returns the reason the session was cancelled, if it timed out
+func (s *session) expired() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		if s.maxTimer != nil {
+			s.maxTimer.Stop()
+		}
+		close(s.done)
+		s.cancel()
+	})
+}
+
+// reader and writer wrap the streams of the session so any data going
+// through them counts as activity
+func (s *session) reader(r io.Reader) io.Reader {
+	return &sessionReader{session: s, reader: r}
+}
+
+func (s *session) writer(w io.Writer) io.Writer {
+	return &sessionWriter{session: s, writer: w}
+}
+
+type sessionReader struct {
+	session *session
+	reader  io.Reader
+}
+
+func (r *sessionReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.session.touch()
+	}
+
+	return n, err
+}
+
+type sessionWriter struct {
+	session *session
+	writer  io.Writer
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if n > 0 {
+		w.session.touch()
+	}
+
+	return n, err
+}
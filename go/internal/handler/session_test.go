@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSessionIdleTimeout(t *testing.T) {
+	ctx, session := startSession(context.Background(), 50*time.Millisecond, 0)
+	defer session.close()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the idle session to be cancelled")
+	}
+
+	err := session.expired()
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.Contains(t, err.Error(), "session was idle for more than 50ms")
+}
+
+func TestSessionActivityPreventsIdleTimeout(t *testing.T) {
+	ctx, session := startSession(context.Background(), 100*time.Millisecond, 0)
+	defer session.close()
+
+	writer := session.writer(ioutil.Discard)
+	for i := 0; i < 6; i++ {
+		time.Sleep(40 * time.Millisecond)
+		_, err := writer.Write([]byte("data"))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, ctx.Err())
+	require.NoError(t, session.expired())
+
+	reader := session.reader(bytes.NewBufferString("data"))
+	_, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+}
+
+func TestSessionMaxDuration(t *testing.T) {
+	ctx, session := startSession(context.Background(), 0, 50*time.Millisecond)
+	defer session.close()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the session to be cancelled")
+	}
+
+	err := session.expired()
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.Contains(t, err.Error(), "session exceeded the maximum duration of 50ms")
+}
+
+func TestSessionWithoutTimeouts(t *testing.T) {
+	ctx, session := startSession(context.Background(), 0, 0)
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, ctx.Err())
+
+	session.close()
+	require.Error(t, ctx.Err())
+	require.NoError(t, session.expired())
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// nowFunc and sleepFunc are overridden in tests
+	nowFunc   = time.Now
+	sleepFunc = time.Sleep
+)
+
+// rateLimiter is a token bucket holding at most one second worth of bytes.
+// Going over the limit puts the bucket into debt, which wait() pays back by
+// sleeping.
+type rateLimiter struct {
+	bytesPerSecond float64
+	tokens         float64
+	last           time.Time
+	mutex          sync.Mutex
+}
+
+func newRateLimiter(bytesPerSecond uint64) *rateLimiter {
+	return &rateLimiter{
+		bytesPerSecond: float64(bytesPerSecond),
+		tokens:         float64(bytesPerSecond),
+		last:           nowFunc(),
+	}
+}
+
+func (l *rateLimiter) wait(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := nowFunc()
+	l.tokens += now.Sub(l.last).Seconds() * l.bytesPerSecond
+	if l.tokens > l.bytesPerSecond {
+		l.tokens = l.bytesPerSecond
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		sleepFunc(time.Duration(-l.tokens / l.bytesPerSecond * float64(time.Second)))
+	}
+}
+
+// maxChunk keeps single reads and writes within the bucket size, so data
+// flows steadily instead of in one-second bursts
+func (l *rateLimiter) maxChunk() int {
+	if l.bytesPerSecond < 1 {
+		return 1
+	}
+
+	return int(l.bytesPerSecond)
+}
+
+// throttleReader and throttleWriter limit a stream to bytesPerSecond, zero
+// leaves the stream unlimited
+func throttleReader(r io.Reader, bytesPerSecond uint64) io.Reader {
+	if bytesPerSecond == 0 {
+		return r
+	}
+
+	return &throttledReader{reader: r, limiter: newRateLimiter(bytesPerSecond)}
+}
+
+func throttleWriter(w io.Writer, bytesPerSecond uint64) io.Writer {
+	if bytesPerSecond == 0 {
+		return w
+	}
+
+	return &throttledWriter{writer: w, limiter: newRateLimiter(bytesPerSecond)}
+}
+
+type throttledReader struct {
+	reader  io.Reader
+	limiter *rateLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if max := r.limiter.maxChunk(); len(p) > max {
+		p = p[:max]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.limiter.wait(n)
+	}
+
+	return n, err
+}
+
+type throttledWriter struct {
+	writer  io.Writer
+	limiter *rateLimiter
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	max := w.limiter.maxChunk()
+
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > max {
+			chunk = chunk[:max]
+		}
+
+		w.limiter.wait(len(chunk))
+		n, err := w.writer.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
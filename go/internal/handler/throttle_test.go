@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stubSleep replaces sleeping with advancing a fake clock, and returns the
+// total time slept
+func stubSleep() (*time.Duration, func()) {
+	var slept time.Duration
+	now := time.Now()
+
+	nowFunc = func() time.Time { return now.Add(slept) }
+	sleepFunc = func(d time.Duration) { slept += d }
+
+	return &slept, func() {
+		nowFunc = time.Now
+		sleepFunc = time.Sleep
+	}
+}
+
+func TestThrottleWriter(t *testing.T) {
+	slept, restore := stubSleep()
+	defer restore()
+
+	out := &bytes.Buffer{}
+	writer := throttleWriter(out, 1000)
+
+	n, err := writer.Write(make([]byte, 3000))
+	require.NoError(t, err)
+	require.Equal(t, 3000, n)
+	require.Equal(t, 3000, out.Len())
+
+	// The first second worth of data is allowed as a burst
+	require.InDelta(t, float64(2*time.Second), float64(*slept), float64(time.Millisecond))
+}
+
+func TestThrottleReader(t *testing.T) {
+	slept, restore := stubSleep()
+	defer restore()
+
+	reader := throttleReader(bytes.NewReader(make([]byte, 2500)), 500)
+
+	data, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Len(t, data, 2500)
+	require.InDelta(t, float64(4*time.Second), float64(*slept), float64(time.Millisecond))
+}
+
+func TestThrottleUnlimited(t *testing.T) {
+	out := &bytes.Buffer{}
+	in := &bytes.Buffer{}
+
+	require.Equal(t, out, throttleWriter(out, 0))
+	require.Equal(t, in, throttleReader(in, 0))
+}
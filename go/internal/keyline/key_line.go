@@ -0,0 +1,101 @@
+// Package keyline formats authorized_keys lines running gitlab-shell, like
+// gitlab-shell-ruby's GitlabKeys.key_line
+package keyline
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/sshkey"
+)
+
+const (
+	PublicKeyPrefix = "key"
+	PrincipalPrefix = "username"
+
+	sshOptions = "no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty"
+)
+
+var (
+	keyIdRegex = regexp.MustCompile(`\A[a-z0-9-]+\z`)
+)
+
+type KeyLine struct {
+	Id      string // The key id or principal name
+	Value   string // The public key or principal
+	Prefix  string // Either PublicKeyPrefix or PrincipalPrefix
+	RootDir string
+	Options Options
+}
+
+// NewPublicKeyLine validates the public key, and normalizes it in the line
+func NewPublicKeyLine(id, publicKey, rootDir string) (*KeyLine, error) {
+	keyLine, err := newKeyLine(id, publicKey, PublicKeyPrefix, rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := sshkey.Parse(keyLine.Value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid public key: %v", err)
+	}
+	keyLine.Value = key.String()
+
+	return keyLine, nil
+}
+
+func NewPrincipalKeyLine(keyId, principal, rootDir string) (*KeyLine, error) {
+	return newKeyLine(keyId, principal, PrincipalPrefix, rootDir)
+}
+
+func newKeyLine(id, value, prefix, rootDir string) (*KeyLine, error) {
+	keyLine := &KeyLine{Id: id, Value: strings.TrimSuffix(value, "\n"), Prefix: prefix, RootDir: rootDir}
+
+	if !keyIdRegex.MatchString(keyLine.command()) {
+		return nil, fmt.Errorf("Invalid key_id: %q", keyLine.command())
+	}
+
+	if strings.Contains(keyLine.Value, "\n") {
+		return nil, fmt.Errorf("Invalid value: %q", keyLine.Value)
+	}
+
+	// The path ends up in the quoted command option
+	if strings.ContainsAny(rootDir, "\"\\\n") {
+		return nil, fmt.Errorf("Invalid root directory: %q", rootDir)
+	}
+
+	return keyLine, nil
+}
+
+// ToString returns the line as it should appear in authorized_keys
+func (k *KeyLine) ToString() string {
+	command := fmt.Sprintf("%s %s", filepath.Join(k.RootDir, "bin", "gitlab-shell"), k.command())
+
+	return fmt.Sprintf(`command="%s",%s %s`, command, k.Options.String(), k.Value)
+}
+
+// SetOptions replaces the options of the line, once they are validated
+func (k *KeyLine) SetOptions(options Options) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	k.Options = options
+
+	return nil
+}
+
+// PublicKey parses the public key of the line
+func (k *KeyLine) PublicKey() (*sshkey.PublicKey, error) {
+	if k.Prefix != PublicKeyPrefix {
+		return nil, fmt.Errorf("%s is not a public key", k.command())
+	}
+
+	return sshkey.Parse(k.Value)
+}
+
+func (k *KeyLine) command() string {
+	return fmt.Sprintf("%s-%s", k.Prefix, k.Id)
+}
@@ -0,0 +1,93 @@
+package keyline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ed25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB1WPaqcY4HOEnrYcVnLTPUT8E9tfc2ZkDWpmXdvTk8P"
+)
+
+func TestToString(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		build    func() (*KeyLine, error)
+		expected string
+	}{
+		{
+			desc:     "A public key",
+			build:    func() (*KeyLine, error) { return NewPublicKeyLine("1", ed25519Key+"\n", "/home/git/gitlab-shell") },
+			expected: `command="/home/git/gitlab-shell/bin/gitlab-shell key-1",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty ` + ed25519Key,
+		},
+		{
+			desc: "A public key with a comment",
+			build: func() (*KeyLine, error) {
+				return NewPublicKeyLine("1", " "+ed25519Key+"  jane  doe ", "/home/git/gitlab-shell")
+			},
+			expected: `command="/home/git/gitlab-shell/bin/gitlab-shell key-1",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty ` + ed25519Key + " jane  doe",
+		},
+		{
+			desc: "A principal",
+			build: func() (*KeyLine, error) {
+				return NewPrincipalKeyLine("jane-doe", "principal", "/home/git/gitlab-shell")
+			},
+			expected: `command="/home/git/gitlab-shell/bin/gitlab-shell username-jane-doe",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty principal`,
+		},
+		{
+			desc: "A principal with options",
+			build: func() (*KeyLine, error) {
+				line, err := NewPrincipalKeyLine("jane-doe", "principal", "/home/git/gitlab-shell")
+				if err != nil {
+					return nil, err
+				}
+
+				return line, line.SetOptions(Options{Restrict: true, From: []string{"10.0.0.0/8"}})
+			},
+			expected: `command="/home/git/gitlab-shell/bin/gitlab-shell username-jane-doe",restrict,from="10.0.0.0/8" principal`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			line, err := tc.build()
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, line.ToString())
+		})
+	}
+}
+
+func TestFailingNewKeyLine(t *testing.T) {
+	_, err := NewPublicKeyLine("1 2", ed25519Key, "/")
+	assert.EqualError(t, err, `Invalid key_id: "key-1 2"`)
+
+	_, err = NewPublicKeyLine("1", "ssh-rsa AAAA\nssh-rsa BBBB", "/")
+	assert.EqualError(t, err, `Invalid value: "ssh-rsa AAAA\nssh-rsa BBBB"`)
+
+	_, err = NewPublicKeyLine("1", ed25519Key, `/tmp" /bin/sh "`)
+	assert.EqualError(t, err, `Invalid root directory: "/tmp\" /bin/sh \""`)
+
+	_, err = NewPublicKeyLine("1", "ssh-rsa AAAA", "/")
+	assert.EqualError(t, err, "Invalid public key: ssh: short read")
+
+	_, err = NewPublicKeyLine("1", "ssh-rsa "+ed25519Key[len("ssh-ed25519 "):], "/")
+	assert.EqualError(t, err, `Invalid public key: key type "ssh-rsa" doesn't match the key data ("ssh-ed25519")`)
+}
+
+func TestPublicKey(t *testing.T) {
+	line, err := NewPublicKeyLine("1", ed25519Key+" jane@example.com", "/")
+	require.NoError(t, err)
+
+	key, err := line.PublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, "SHA256:nIaNY9q8rz8HmE+S9GW2KzVgZuMUj5lf/cxHZvFswbY", key.Fingerprint())
+
+	line, err = NewPrincipalKeyLine("1", "principal", "/")
+	require.NoError(t, err)
+
+	_, err = line.PublicKey()
+	assert.EqualError(t, err, "username-1 is not a public key")
+}
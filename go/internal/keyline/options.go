@@ -0,0 +1,82 @@
+package keyline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+const (
+	// expiryTimeFormat is the YYYYMMDDHHMMSS format of expiry-time, which
+	// sshd reads in the local time zone
+	expiryTimeFormat = "20060102150405"
+)
+
+var (
+	fromPatternRegex = regexp.MustCompile(`\A!?[A-Za-z0-9.:*?/_\[\]-]+\z`)
+	environmentRegex = regexp.MustCompile(`\A[A-Za-z_][A-Za-z0-9_]*=[^"\\\x00-\x1f\x7f]*\z`)
+)
+
+// Options are the authorized_keys options of a line besides command. The
+// zero value gives the options gitlab-keys writes.
+type Options struct {
+	Restrict    bool      // Use restrict instead of the no-* options
+	From        []string  // Patterns of the client addresses allowed
+	Environment []string  // NAME=value variables
+	ExpiryTime  time.Time // When the key expires, unless zero
+}
+
+// NewOptions returns the options set in the config. expiresAt is the expiry
+// of the key returned by the API, if any.
+func NewOptions(cfg config.KeyOptionsConfig, expiresAt *time.Time) Options {
+	options := Options{Restrict: cfg.Restrict, From: cfg.From, Environment: cfg.Environment}
+
+	if cfg.ExpiryTime && expiresAt != nil {
+		options.ExpiryTime = *expiresAt
+	}
+
+	return options
+}
+
+// Validate makes sure the options can't break out of their quotes, and so
+// inject other options or change the command
+func (o Options) Validate() error {
+	for _, pattern := range o.From {
+		if !fromPatternRegex.MatchString(pattern) {
+			return fmt.Errorf("Invalid from pattern: %q", pattern)
+		}
+	}
+
+	for _, variable := range o.Environment {
+		if !environmentRegex.MatchString(variable) {
+			return fmt.Errorf("Invalid environment variable: %q", variable)
+		}
+	}
+
+	return nil
+}
+
+// String returns the options as they appear in authorized_keys
+func (o Options) String() string {
+	options := []string{sshOptions}
+	if o.Restrict {
+		options = []string{"restrict"}
+	}
+
+	if len(o.From) > 0 {
+		options = append(options, fmt.Sprintf(`from="%s"`, strings.Join(o.From, ",")))
+	}
+
+	if !o.ExpiryTime.IsZero() {
+		options = append(options, fmt.Sprintf(`expiry-time="%s"`, o.ExpiryTime.Local().Format(expiryTimeFormat)))
+	}
+
+	for _, variable := range o.Environment {
+		options = append(options, fmt.Sprintf(`environment="%s"`, variable))
+	}
+
+	return strings.Join(options, ",")
+}
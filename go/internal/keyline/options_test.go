@@ -0,0 +1,107 @@
+package keyline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitlab-shell/go/internal/config"
+)
+
+func TestOptionsString(t *testing.T) {
+	expiresAt := time.Date(2026, 10, 19, 12, 30, 0, 0, time.Local)
+
+	testCases := []struct {
+		desc     string
+		options  Options
+		expected string
+	}{
+		{
+			desc:     "The default options",
+			options:  Options{},
+			expected: "no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty",
+		},
+		{
+			desc:     "With restrict",
+			options:  Options{Restrict: true},
+			expected: "restrict",
+		},
+		{
+			desc: "With every option",
+			options: Options{
+				From:        []string{"10.0.0.0/8", "!10.0.0.1", "*.example.com"},
+				Environment: []string{"A=b c", "EMPTY="},
+				ExpiryTime:  expiresAt,
+			},
+			expected: `no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty,` +
+				`from="10.0.0.0/8,!10.0.0.1,*.example.com",expiry-time="20261019123000",environment="A=b c",environment="EMPTY="`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.NoError(t, tc.options.Validate())
+			assert.Equal(t, tc.expected, tc.options.String())
+		})
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	expiresAt := time.Now()
+	cfg := config.KeyOptionsConfig{Restrict: true, From: []string{"10.0.0.0/8"}}
+
+	assert.Equal(t, Options{Restrict: true, From: []string{"10.0.0.0/8"}}, NewOptions(cfg, &expiresAt))
+
+	cfg.ExpiryTime = true
+	assert.Equal(t, expiresAt, NewOptions(cfg, &expiresAt).ExpiryTime)
+	assert.True(t, NewOptions(cfg, nil).ExpiryTime.IsZero())
+}
+
+func TestFailingOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		options       Options
+		expectedError string
+	}{
+		{
+			options:       Options{From: []string{`10.0.0.1",command="/bin/sh`}},
+			expectedError: `Invalid from pattern: "10.0.0.1\",command=\"/bin/sh"`,
+		},
+		{
+			options:       Options{From: []string{""}},
+			expectedError: `Invalid from pattern: ""`,
+		},
+		{
+			options:       Options{Environment: []string{`A=b",command="/bin/sh`}},
+			expectedError: `Invalid environment variable: "A=b\",command=\"/bin/sh"`,
+		},
+		{
+			options:       Options{Environment: []string{"A=b\\"}},
+			expectedError: `Invalid environment variable: "A=b\\"`,
+		},
+		{
+			options:       Options{Environment: []string{"A=b\nc"}},
+			expectedError: `Invalid environment variable: "A=b\nc"`,
+		},
+		{
+			options:       Options{Environment: []string{"1A=b"}},
+			expectedError: `Invalid environment variable: "1A=b"`,
+		},
+		{
+			options:       Options{Environment: []string{"NAME"}},
+			expectedError: `Invalid environment variable: "NAME"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expectedError, func(t *testing.T) {
+			assert.EqualError(t, tc.options.Validate(), tc.expectedError)
+
+			line, err := NewPrincipalKeyLine("1", "principal", "/")
+			require.NoError(t, err)
+			assert.Error(t, line.SetOptions(tc.options))
+			assert.Equal(t, Options{}, line.Options)
+		})
+	}
+}
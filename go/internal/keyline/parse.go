@@ -0,0 +1,138 @@
+package keyline
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	commandOption = `command="`
+)
+
+// Parse reads a line written by ToString, or by gitlab-keys which uses the
+// same format. Other options than command are ignored.
+func Parse(line, rootDir string) (*KeyLine, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, commandOption) {
+		return nil, fmt.Errorf("missing command option")
+	}
+
+	options, value := splitOptions(line)
+	if value == "" {
+		return nil, fmt.Errorf("missing key")
+	}
+
+	command := strings.TrimPrefix(options, commandOption)
+	end := strings.Index(command, `"`)
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated command option")
+	}
+
+	// The command is `/path/to/bin/gitlab-shell key-1`
+	fields := strings.Fields(command[:end])
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid command %q", command[:end])
+	}
+
+	parts := strings.SplitN(fields[1], "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid command %q", command[:end])
+	}
+
+	return newKeyLine(parts[1], value, parts[0], rootDir)
+}
+
+// splitOptions splits the line at the first whitespace outside of quotes,
+// which ends the options
+func splitOptions(line string) (string, string) {
+	quoted := false
+
+	for i, char := range line {
+		switch {
+		case char == '"':
+			quoted = !quoted
+		case !quoted && (char == ' ' || char == '\t'):
+			return line[:i], strings.TrimSpace(line[i+1:])
+		}
+	}
+
+	return line, ""
+}
+
+// Blob returns the base64 encoded key of a public key line, which sshd
+// passes to gitlab-shell-authorized-keys-check
+func (k *KeyLine) Blob() string {
+	return KeyBlob(k.Value)
+}
+
+// KeyBlob returns the base64 encoded part of a public key, e.g. AAAA... for
+// `ssh-rsa AAAA... jane@example.com`
+func KeyBlob(publicKey string) string {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 {
+		return ""
+	}
+
+	return fields[1]
+}
+
+// Index finds public key lines of an authorized_keys file by their blob
+type Index struct {
+	lines map[string]*KeyLine
+}
+
+// ReadFile returns the lines of the authorized_keys file at path written by
+// gitlab-shell. Other lines, like comments and keys removed by gitlab-keys,
+// are skipped.
+func ReadFile(path, rootDir string) ([]*KeyLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []*KeyLine
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line, err := Parse(scanner.Text(), rootDir); err == nil {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// LoadIndex indexes the public keys of the authorized_keys file at path
+func LoadIndex(path, rootDir string) (*Index, error) {
+	lines, err := ReadFile(path, rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{lines: map[string]*KeyLine{}}
+	for _, line := range lines {
+		if line.Prefix != PublicKeyPrefix {
+			continue
+		}
+
+		// Like sshd, use the first line matching a key
+		if blob := line.Blob(); blob != "" && index.lines[blob] == nil {
+			index.lines[blob] = line
+		}
+	}
+
+	return index, nil
+}
+
+// Find returns the line for a key blob, or nil if there is none
+func (i *Index) Find(blob string) *KeyLine {
+	return i.lines[blob]
+}
@@ -0,0 +1,92 @@
+package keyline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		line     string
+		expected *KeyLine
+	}{
+		{
+			desc:     "A public key line",
+			line:     `command="/home/git/gitlab-shell/bin/gitlab-shell key-741",no-port-forwarding,no-X11-forwarding,no-agent-forwarding,no-pty ssh-rsa AAAAB3NzaC1 jane@example.com` + "\n",
+			expected: &KeyLine{Id: "741", Value: "ssh-rsa AAAAB3NzaC1 jane@example.com", Prefix: PublicKeyPrefix, RootDir: "/root"},
+		},
+		{
+			desc:     "A principal line",
+			line:     `command="/home/git/gitlab-shell/bin/gitlab-shell username-jane-doe",no-pty principal`,
+			expected: &KeyLine{Id: "jane-doe", Value: "principal", Prefix: PrincipalPrefix, RootDir: "/root"},
+		},
+		{
+			desc:     "A line with quoted options",
+			line:     `command="/bin/gitlab-shell key-1",environment="A=b c",no-pty ecdsa-sha2-nistp256 AAAAE2VjZHNh`,
+			expected: &KeyLine{Id: "1", Value: "ecdsa-sha2-nistp256 AAAAE2VjZHNh", Prefix: PublicKeyPrefix, RootDir: "/root"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := Parse(tc.line, "/root")
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestFailingParse(t *testing.T) {
+	testCases := []struct {
+		line          string
+		expectedError string
+	}{
+		{line: "ssh-rsa AAAA", expectedError: "missing command option"},
+		{line: "###########", expectedError: "missing command option"},
+		{line: `command="/bin/gitlab-shell key-1",no-pty`, expectedError: "missing key"},
+		{line: `command="/bin/gitlab-shell",no-pty ssh-rsa AAAA`, expectedError: `invalid command "/bin/gitlab-shell"`},
+		{line: `command="/bin/gitlab-shell key-1 2",no-pty ssh-rsa AAAA`, expectedError: `invalid command "/bin/gitlab-shell key-1 2"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			_, err := Parse(tc.line, "/root")
+
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	authFile := filepath.Join(dir, "authorized_keys")
+	content := "# Managed by gitlab-shell\n" +
+		`command="/bin/gitlab-shell key-1",no-pty ssh-rsa AAAA1 jane@example.com` + "\n" +
+		"###############################################\n" +
+		`command="/bin/gitlab-shell key-2",no-pty ssh-ed25519 AAAA2` + "\n" +
+		`command="/bin/gitlab-shell key-3",no-pty ssh-ed25519 AAAA2` + "\n" +
+		`command="/bin/gitlab-shell username-jane",no-pty AAAA4` + "\n" +
+		"ssh-rsa AAAA5 someone@example.com\n"
+	require.NoError(t, ioutil.WriteFile(authFile, []byte(content), 0600))
+
+	index, err := LoadIndex(authFile, "/root")
+	require.NoError(t, err)
+
+	assert.Equal(t, &KeyLine{Id: "1", Value: "ssh-rsa AAAA1 jane@example.com", Prefix: PublicKeyPrefix, RootDir: "/root"}, index.Find("AAAA1"))
+	assert.Equal(t, "2", index.Find("AAAA2").Id)
+	assert.Nil(t, index.Find("AAAA4"))
+	assert.Nil(t, index.Find("AAAA5"))
+
+	_, err = LoadIndex(filepath.Join(dir, "missing"), "/root")
+	assert.True(t, os.IsNotExist(err))
+}
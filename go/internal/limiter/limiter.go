@@ -0,0 +1,122 @@
+package limiter
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrLimitExceeded = errors.New("Concurrency limit exceeded")
+
+	// pollInterval is overridden in tests
+	pollInterval = 100 * time.Millisecond
+)
+
+// Limiter bounds the number of concurrent sessions sharing a key. The
+// sessions run in independent gitlab-shell processes, so every slot is a lock
+// file: holding an exclusive flock(2) on it means holding the slot. The
+// kernel drops the lock when the process exits, so a crashed session never
+// leaks its slot. Releasing a slot removes its lock file, so the directory
+// only holds the slots in use, and those of crashed sessions.
+type Limiter struct {
+	Dir string
+}
+
+// Acquire takes one of limit slots for key, waiting up to timeout for one to
+// become available. It returns ErrLimitExceeded if none did. The returned
+// function releases the slot.
+func (l *Limiter) Acquire(ctx context.Context, key string, limit int, timeout time.Duration) (func(), error) {
+	if err := os.MkdirAll(l.Dir, 0700); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		release, err := l.tryAcquire(key, limit)
+		if err != nil || release != nil {
+			return release, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrLimitExceeded
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (l *Limiter) tryAcquire(key string, limit int) (func(), error) {
+	prefix := fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+
+	for slot := 0; slot < limit; slot++ {
+		path := filepath.Join(l.Dir, fmt.Sprintf("%s.%d.lock", prefix, slot))
+
+		file, err := lockFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if file != nil {
+			return func() {
+				// Removing the file while holding the lock is safe: sessions
+				// that opened it meanwhile notice and try again
+				os.Remove(path)
+				file.Close()
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// lockFile takes the lock of the file at path, creating it if needed. It
+// returns nil if another session holds the lock.
+func lockFile(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == syscall.EWOULDBLOCK {
+			file.Close()
+			return nil, nil
+		}
+
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		// The session releasing the slot may have removed the file between
+		// opening and locking it, leaving us a lock nobody else can see
+		if isCurrent(file, path) {
+			return file, nil
+		}
+
+		file.Close()
+	}
+}
+
+func isCurrent(file *os.File, path string) bool {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(fileInfo, pathInfo)
+}
@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T) (*Limiter, func()) {
+	dir, err := ioutil.TempDir("", "gitlab-shell-limiter")
+	require.NoError(t, err)
+
+	pollInterval = 10 * time.Millisecond
+
+	return &Limiter{Dir: dir}, func() { os.RemoveAll(dir) }
+}
+
+func TestAcquireWithinLimit(t *testing.T) {
+	limiter, cleanup := setup(t)
+	defer cleanup()
+
+	release1, err := limiter.Acquire(context.Background(), "user:key-1", 2, 0)
+	require.NoError(t, err)
+	defer release1()
+
+	release2, err := limiter.Acquire(context.Background(), "user:key-1", 2, 0)
+	require.NoError(t, err)
+	defer release2()
+
+	// Other keys have their own slots
+	release3, err := limiter.Acquire(context.Background(), "user:key-2", 2, 0)
+	require.NoError(t, err)
+	defer release3()
+}
+
+func TestAcquireOverLimit(t *testing.T) {
+	limiter, cleanup := setup(t)
+	defer cleanup()
+
+	release, err := limiter.Acquire(context.Background(), "user:key-1", 1, 0)
+	require.NoError(t, err)
+
+	_, err = limiter.Acquire(context.Background(), "user:key-1", 1, 50*time.Millisecond)
+	require.Equal(t, ErrLimitExceeded, err)
+
+	release()
+
+	release, err = limiter.Acquire(context.Background(), "user:key-1", 1, 0)
+	require.NoError(t, err)
+	release()
+}
+
+func TestReleaseRemovesLockFiles(t *testing.T) {
+	limiter, cleanup := setup(t)
+	defer cleanup()
+
+	release1, err := limiter.Acquire(context.Background(), "user:key-1", 2, 0)
+	require.NoError(t, err)
+
+	release2, err := limiter.Acquire(context.Background(), "user:key-1", 2, 0)
+	require.NoError(t, err)
+
+	files, err := ioutil.ReadDir(limiter.Dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	release1()
+	release2()
+
+	files, err = ioutil.ReadDir(limiter.Dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestAcquireWaitsForSlot(t *testing.T) {
+	limiter, cleanup := setup(t)
+	defer cleanup()
+
+	release, err := limiter.Acquire(context.Background(), "repository:project-1", 1, 0)
+	require.NoError(t, err)
+
+	time.AfterFunc(50*time.Millisecond, release)
+
+	queued, err := limiter.Acquire(context.Background(), "repository:project-1", 1, time.Second)
+	require.NoError(t, err)
+	queued()
+}
@@ -55,6 +55,43 @@ func logPrint(msg string, err error) {
 	}).Error(msg)
 }
 
+// Error logs the error without showing anything to the end user
+func Error(msg string, err error) {
+	logPrint(msg, err)
+}
+
+// Warn logs a warning with extra fields, without showing anything to the end
+// user
+func Warn(msg string, fields map[string]interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logWriter == nil {
+		bootstrapLogPrint(msg, fields)
+		return
+	}
+
+	log.WithFields(fields).WithFields(log.Fields{
+		"pid": pid,
+	}).Warn(msg)
+}
+
+// Info logs a message with extra fields, without showing anything to the
+// end user
+func Info(msg string, fields map[string]interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logWriter == nil {
+		bootstrapLogPrint(msg, fields)
+		return
+	}
+
+	log.WithFields(fields).WithFields(log.Fields{
+		"pid": pid,
+	}).Info(msg)
+}
+
 func Fatal(msg string, err error) {
 	logPrint(msg, err)
 	// We don't show the error to the end user because it can leak
@@ -68,7 +105,7 @@ func Fatal(msg string, err error) {
 // function attemps to log to syslog.
 //
 // We assume the logging mutex is already locked.
-func bootstrapLogPrint(msg string, err error) {
+func bootstrapLogPrint(msg string, details interface{}) {
 	if bootstrapLogger == nil {
 		var err error
 		bootstrapLogger, err = syslog.NewLogger(syslog.LOG_ERR|syslog.LOG_USER, 0)
@@ -78,5 +115,5 @@ func bootstrapLogPrint(msg string, err error) {
 		}
 	}
 
-	bootstrapLogger.Print(ProgName+":", msg+":", err)
+	bootstrapLogger.Print(ProgName+":", msg+":", details)
 }
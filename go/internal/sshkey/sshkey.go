@@ -0,0 +1,104 @@
+// Package sshkey parses OpenSSH public keys in the authorized_keys format,
+// including FIDO (sk-*) keys and certificates, with golang.org/x/crypto/ssh
+package sshkey
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PublicKey is a public key as found in authorized_keys files, e.g.
+// `ssh-ed25519 AAAAC3Nza... jane@example.com`
+type PublicKey struct {
+	Key     ssh.PublicKey
+	Comment string
+}
+
+// Parse parses and validates a single public key without options. The type
+// must be supported and match the key data. Whitespace around the comment is
+// trimmed, and kept inside of it.
+func Parse(publicKey string) (*PublicKey, error) {
+	key, comment, options, rest, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return nil, parseError(publicKey)
+	}
+
+	if len(options) > 0 || strings.TrimSpace(string(rest)) != "" {
+		return nil, errors.New("public key must be a single key without options")
+	}
+
+	// ParseAuthorizedKey only looks at the type in the key data
+	if keyType := strings.Fields(publicKey)[0]; keyType != key.Type() {
+		return nil, fmt.Errorf("key type %q doesn't match the key data (%q)", keyType, key.Type())
+	}
+
+	return &PublicKey{Key: key, Comment: comment}, nil
+}
+
+// ParseBlob parses and validates the base64 encoded part of a public key,
+// which is what sshd passes to AuthorizedKeysCommand as %k
+func ParseBlob(encodedBlob string) (*PublicKey, error) {
+	blob, err := base64.StdEncoding.DecodeString(encodedBlob)
+	if err != nil {
+		return nil, errors.New("public key is not valid base64")
+	}
+
+	key, err := ssh.ParsePublicKey(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKey{Key: key}, nil
+}
+
+// Type returns the type of the key, e.g. ssh-ed25519
+func (k *PublicKey) Type() string {
+	return k.Key.Type()
+}
+
+// String returns the key in the authorized_keys format
+func (k *PublicKey) String() string {
+	key := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k.Key)))
+	if k.Comment != "" {
+		key += " " + k.Comment
+	}
+
+	return key
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key, like
+// `ssh-keygen -l -E sha256`. As there, certificates have the fingerprint of
+// the key they certify.
+func (k *PublicKey) Fingerprint() string {
+	if cert, ok := k.Key.(*ssh.Certificate); ok {
+		return ssh.FingerprintSHA256(cert.Key)
+	}
+
+	return ssh.FingerprintSHA256(k.Key)
+}
+
+// IsCertificate tells whether the key is an OpenSSH certificate
+func (k *PublicKey) IsCertificate() bool {
+	_, ok := k.Key.(*ssh.Certificate)
+
+	return ok
+}
+
+// parseError explains why ParseAuthorizedKey found no key, which it doesn't
+// tell
+func parseError(publicKey string) error {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 {
+		return errors.New("public key must have a type and a key")
+	}
+
+	if _, err := ParseBlob(fields[1]); err != nil {
+		return err
+	}
+
+	return errors.New("invalid public key")
+}
@@ -0,0 +1,195 @@
+package sshkey
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rsaKey     = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDhI/FW0RAfp6JU9U4Eb7lDxWrvyrpdf/ehnKCJNd9ee6B5UO9JCAyuSkU7ipCgX+HLDE6oYgCziSux4XTSWyK1CISbd19oLuGQl9FAMHDQ3s2iVrT5xRoOtCXzl7SsW49G2miuNDIqsnlBptxYPFQ5J0aeHbU33/cvgsWFadbh+Q=="
+	ed25519Key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB1WPaqcY4HOEnrYcVnLTPUT8E9tfc2ZkDWpmXdvTk8P"
+	ecdsaKey   = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBPvjXnvFm4N44qwdVa+IoQGcqd/8sJFheUPQ3ThiUa/7C45UJS7AVHusIQSyJ52NpLPom4SWyvYRbWzQprxa/Xc="
+	skKey      = "sk-ssh-ed25519@openssh.com AAAAGnNrLXNzaC1lZDI1NTE5QG9wZW5zc2guY29tAAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4fAAAABHNzaDo="
+	certKey    = "ssh-ed25519-cert-v01@openssh.com AAAAIHNzaC1lZDI1NTE5LWNlcnQtdjAxQG9wZW5zc2guY29tAAAAIPmpHE20MvkIyq/qaVSPv/oRvruyPHSfW4SiI+NsbGpvAAAAIB1WPaqcY4HOEnrYcVnLTPUT8E9tfc2ZkDWpmXdvTk8PAAAAAAAAAAAAAAABAAAABGphbmUAAAAHAAAAA2dpdAAAAAAAAAAA//////////8AAAAAAAAAggAAABVwZXJtaXQtWDExLWZvcndhcmRpbmcAAAAAAAAAF3Blcm1pdC1hZ2VudC1mb3J3YXJkaW5nAAAAAAAAABZwZXJtaXQtcG9ydC1mb3J3YXJkaW5nAAAAAAAAAApwZXJtaXQtcHR5AAAAAAAAAA5wZXJtaXQtdXNlci1yYwAAAAAAAAAAAAAAMwAAAAtzc2gtZWQyNTUxOQAAACDRwhs04on1CdP4kxxjWtL8GiXsv4kUrulsMZOSOPjtfQAAAFMAAAALc3NoLWVkMjU1MTkAAABAl7VyFpwJ6F6OVNqkvgAtCGuIiqj8fW1z2YhKrlii7GUbYcv76bp209MaqwTCRlvmvONLHgC9bC+4Iy5hxixIDg=="
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		publicKey           string
+		expectedType        string
+		expectedComment     string
+		expectedFingerprint string
+		expectedCertificate bool
+	}{
+		{
+			desc:                "An RSA key",
+			publicKey:           rsaKey + " jane@example.com",
+			expectedType:        "ssh-rsa",
+			expectedComment:     "jane@example.com",
+			expectedFingerprint: "SHA256:2uGLfmJGusc4Hh6mPZQytS5+oUplrmXQliqU46wUdac",
+		},
+		{
+			desc:                "An ed25519 key",
+			publicKey:           ed25519Key,
+			expectedType:        "ssh-ed25519",
+			expectedFingerprint: "SHA256:nIaNY9q8rz8HmE+S9GW2KzVgZuMUj5lf/cxHZvFswbY",
+		},
+		{
+			desc:                "An ECDSA key",
+			publicKey:           ecdsaKey,
+			expectedType:        "ecdsa-sha2-nistp256",
+			expectedFingerprint: "SHA256:CcN4PT+T5iOoTVpnpfU0Zl4v4gsFFVwLZj3o1+U6was",
+		},
+		{
+			desc:                "A FIDO key",
+			publicKey:           skKey + " jane@example.com",
+			expectedType:        "sk-ssh-ed25519@openssh.com",
+			expectedComment:     "jane@example.com",
+			expectedFingerprint: "SHA256:/p0CbeE3dk2SyW1OXXsThGc12ezDVD8eGw2/vtztDfk",
+		},
+		{
+			desc:                "A certificate has the fingerprint of its key",
+			publicKey:           certKey,
+			expectedType:        "ssh-ed25519-cert-v01@openssh.com",
+			expectedFingerprint: "SHA256:nIaNY9q8rz8HmE+S9GW2KzVgZuMUj5lf/cxHZvFswbY",
+			expectedCertificate: true,
+		},
+		{
+			desc:                "Whitespace around the comment is trimmed",
+			publicKey:           "  " + ed25519Key + "\tjane  doe \n",
+			expectedType:        "ssh-ed25519",
+			expectedComment:     "jane  doe",
+			expectedFingerprint: "SHA256:nIaNY9q8rz8HmE+S9GW2KzVgZuMUj5lf/cxHZvFswbY",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			key, err := Parse(tc.publicKey)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedType, key.Type())
+			assert.Equal(t, tc.expectedComment, key.Comment)
+			assert.Equal(t, tc.expectedFingerprint, key.Fingerprint())
+			assert.Equal(t, tc.expectedCertificate, key.IsCertificate())
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		publicKey      string
+		expectedString string
+	}{
+		{
+			desc:           "A key with a comment",
+			publicKey:      ed25519Key + "   jane  doe ",
+			expectedString: ed25519Key + " jane  doe",
+		},
+		{
+			desc:           "A FIDO key",
+			publicKey:      skKey,
+			expectedString: skKey,
+		},
+		{
+			desc:           "A certificate",
+			publicKey:      certKey + " jane",
+			expectedString: certKey + " jane",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			key, err := Parse(tc.publicKey)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedString, key.String())
+		})
+	}
+}
+
+func TestFailingParse(t *testing.T) {
+	blob := func(fields ...string) string {
+		var data []byte
+		for _, field := range fields {
+			data = append(data, 0, 0, 0, byte(len(field)))
+			data = append(data, field...)
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	testCases := []struct {
+		desc          string
+		publicKey     string
+		expectedError string
+	}{
+		{
+			desc:          "Without a key",
+			publicKey:     "ssh-rsa",
+			expectedError: "public key must have a type and a key",
+		},
+		{
+			desc:          "With invalid base64",
+			publicKey:     "ssh-rsa AAAA!",
+			expectedError: "public key is not valid base64",
+		},
+		{
+			desc:          "With a truncated key",
+			publicKey:     "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDh",
+			expectedError: "ssh: short read",
+		},
+		{
+			desc:          "With a type not matching the key",
+			publicKey:     "ssh-rsa " + ed25519Key[len("ssh-ed25519 "):],
+			expectedError: `key type "ssh-rsa" doesn't match the key data ("ssh-ed25519")`,
+		},
+		{
+			desc:          "With an unsupported type",
+			publicKey:     "ssh-foo " + blob("ssh-foo", "key"),
+			expectedError: "ssh: unknown key algorithm: ssh-foo",
+		},
+		{
+			desc:          "With a mismatched curve",
+			publicKey:     "ecdsa-sha2-nistp384 " + blob("ecdsa-sha2-nistp384", "nistp256", "point"),
+			expectedError: "ssh: invalid curve point",
+		},
+		{
+			desc:          "With a short ed25519 key",
+			publicKey:     "ssh-ed25519 " + blob("ssh-ed25519", "short"),
+			expectedError: "invalid size 5 for Ed25519 public key",
+		},
+		{
+			desc:          "With trailing data",
+			publicKey:     "sk-ssh-ed25519@openssh.com " + blob("sk-ssh-ed25519@openssh.com", string(make([]byte, 32)), "ssh:", "extra"),
+			expectedError: "ssh: trailing junk in public key",
+		},
+		{
+			desc:          "With options",
+			publicKey:     "no-pty " + ed25519Key,
+			expectedError: "public key must be a single key without options",
+		},
+		{
+			desc:          "With several keys",
+			publicKey:     ed25519Key + "\n" + rsaKey,
+			expectedError: "public key must be a single key without options",
+		},
+		{
+			desc:          "With a truncated certificate",
+			publicKey:     "ssh-ed25519-cert-v01@openssh.com " + blob("ssh-ed25519-cert-v01@openssh.com", "nonce", string(make([]byte, 32))),
+			expectedError: "ssh: parse error in message type 0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := Parse(tc.publicKey)
+
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -4,7 +4,7 @@ class GitlabConfig
   attr_reader :config
 
   def initialize
-    @config = YAML.load_file(File.join(ROOT_PATH, 'config.yml'))
+    @config = YAML.load_file(CONFIG_PATH)
   end
 
   def home
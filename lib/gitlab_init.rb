@@ -1,4 +1,5 @@
 ROOT_PATH = ENV.fetch('GITLAB_SHELL_DIR', File.expand_path('..', __dir__))
+CONFIG_PATH = ENV.fetch('GITLAB_SHELL_CONFIG', File.join(ROOT_PATH, 'config.yml'))
 
 # We are transitioning parts of gitlab-shell into the gitaly project. In
 # gitaly, GITALY_EMBEDDED will be true.
@@ -7,6 +7,30 @@ require_relative 'gitlab_metrics'
 class GitlabKeys # rubocop:disable Metrics/ClassLength
   class KeyError < StandardError; end
 
+  # The key types OpenSSH accepts, including FIDO (sk-*) keys and
+  # certificates. Keys are only validated by the Go keys tooling, this is
+  # for finding them in the auth_file.
+  KEY_TYPES = %w[
+    ssh-rsa
+    ssh-dss
+    ssh-ed25519
+    ecdsa-sha2-nistp256
+    ecdsa-sha2-nistp384
+    ecdsa-sha2-nistp521
+    sk-ecdsa-sha2-nistp256@openssh.com
+    sk-ssh-ed25519@openssh.com
+    ssh-rsa-cert-v01@openssh.com
+    ssh-dss-cert-v01@openssh.com
+    ssh-ed25519-cert-v01@openssh.com
+    ecdsa-sha2-nistp256-cert-v01@openssh.com
+    ecdsa-sha2-nistp384-cert-v01@openssh.com
+    ecdsa-sha2-nistp521-cert-v01@openssh.com
+    sk-ecdsa-sha2-nistp256-cert-v01@openssh.com
+    sk-ssh-ed25519-cert-v01@openssh.com
+  ].freeze
+
+  KEY_TYPE_REGEX = Regexp.union(KEY_TYPES)
+
   attr_accessor :auth_file, :key
 
   def self.command(whatever)
@@ -96,7 +120,7 @@ class GitlabKeys # rubocop:disable Metrics/ClassLength
       # key_id & public_key
       # command=".../bin/gitlab-shell key-741" ... ssh-rsa AAAAB3NzaDAxx2E\n
       #                               ^^^^^^^              ^^^^^^^^^^^^^^^
-      matches = /^command=\".+?\s+(.+?)\".+?(?:ssh|ecdsa)-.*?\s(.+)\s*.*\n*$/.match(line)
+      matches = /^command=\".+?\s+(.+?)\".+?\s(?:#{KEY_TYPE_REGEX})\s(.+)\s*.*\n*$/.match(line)
       keys << "#{matches[1]} #{matches[2]}\n" unless matches.nil?
     end
     puts keys
@@ -2,6 +2,7 @@
 
 require 'shellwords'
 require 'pathname'
+require 'tempfile'
 
 require_relative 'gitlab_net'
 require_relative 'gitlab_metrics'
@@ -65,6 +66,7 @@ class GitlabShell # rubocop:disable Metrics/ClassLength
       @gitaly = access_status.gitaly
       @username = access_status.gl_username
       @git_config_options = access_status.git_config_options
+      @rate_limit = access_status.rate_limit
       @gl_id = access_status.gl_id if defined?(@who)
 
       write_stderr(access_status.gl_console_messages)
@@ -170,7 +172,7 @@ class GitlabShell # rubocop:disable Metrics/ClassLength
 
     # TODO: instead of building from pieces here in gitlab-shell, build the
     # entire gitaly_request in gitlab-ce and pass on as-is here.
-    args = JSON.dump(
+    request = {
       'repository' => @gitaly['repository'],
       'gl_repository' => @gl_repository,
       'gl_project_path' => @gl_project_path,
@@ -178,7 +180,10 @@ class GitlabShell # rubocop:disable Metrics/ClassLength
       'gl_username' => @username,
       'git_config_options' => @git_config_options,
       'git_protocol' => @git_protocol
-    )
+    }
+    # Overrides the rate limits configured for the gitaly-* executables
+    request['rate_limit'] = @rate_limit if @rate_limit
+    args = JSON.dump(request)
 
     gitaly_address = @gitaly['address']
     executable = GITALY_COMMANDS.fetch(@command)
@@ -191,11 +196,29 @@ class GitlabShell # rubocop:disable Metrics/ClassLength
 
   # This method is not covered by Rspec because it ends the current Ruby process.
   def exec_cmd(executable, gitaly_address:, token:, json_args:)
-    env = { 'GITALY_TOKEN' => token }
-
-    args = [executable, gitaly_address, json_args]
+    # The Gitaly executable finds its config the same way we did
+    env = {
+      'GITALY_TOKEN' => token,
+      'GITLAB_SHELL_DIR' => ROOT_PATH,
+      'GITLAB_SHELL_CONFIG' => CONFIG_PATH
+    }
+
+    # The request contains user and repository details. Pass it in a file only
+    # we can read instead of argv, which anyone on the host can see with `ps`.
+    # The Gitaly executable removes the file once it has read it.
+    request_file = Tempfile.new('gitaly-request')
+    request_file.write(json_args)
+    request_file.close
+
+    args = [executable, gitaly_address, "--request-file=#{request_file.path}"]
     # We use 'chdir: ROOT_PATH' to let the next executable know where config.yml is.
-    Kernel.exec(env, *args, unsetenv_others: true, chdir: ROOT_PATH)
+    begin
+      Kernel.exec(env, *args, unsetenv_others: true, chdir: ROOT_PATH)
+    rescue SystemCallError
+      # Nothing will read the request then
+      request_file.unlink
+      raise
+    end
   end
 
   def api
@@ -97,8 +97,8 @@ describe GitlabKeys do
 
   describe ':list_keys' do
     let(:gitlab_keys) { build_gitlab_keys('list_keys') }
-    let(:key_data) { "%s\n%s\n" % [described_class.key_line('key-741', 'ssh-rsa AAAAB3NzaDAxx2E'), described_class.key_line('key-742', 'ssh-rsa AAAAB3NzaDAxx2E')] }
-    let(:key_output) { "key-741 AAAAB3NzaDAxx2E\nkey-742 AAAAB3NzaDAxx2E\n" }
+    let(:key_data) { "%s\n%s\n" % [described_class.key_line('key-741', 'ssh-rsa AAAAB3NzaDAxx2E'), described_class.key_line('key-742', 'sk-ssh-ed25519@openssh.com AAAAGnNrLXNzaDAx')] }
+    let(:key_output) { "key-741 AAAAB3NzaDAxx2E\nkey-742 AAAAGnNrLXNzaDAx\n" }
 
     before do
       create_authorized_keys_fixture(
@@ -238,6 +238,20 @@ describe GitlabShell do
         allow_any_instance_of(GitlabConfig).to receive(:audit_usernames).and_return(true)
         expect($logger).to receive(:info).with("executing git command", hash_including(user: 'testuser'))
       end
+
+      context 'with a rate limit' do
+        let(:rate_limit) { { 'download_bytes_per_second' => 1048576 } }
+
+        before do
+          allow(gitaly_check_access).to receive(:rate_limit).and_return(rate_limit)
+        end
+
+        it "should pass the rate limit on to gitaly-upload-pack" do
+          message = JSON.dump(JSON.parse(gitaly_message).merge('rate_limit' => rate_limit))
+
+          expect(subject).to receive(:exec_cmd).with(File.join(ROOT_PATH, "bin/gitaly-upload-pack"), gitaly_address: 'unix:gitaly.socket', json_args: message, token: nil)
+        end
+      end
     end
 
     context 'git-receive-pack' do